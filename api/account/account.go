@@ -73,3 +73,27 @@ type Client interface {
 	// should be returned.
 	SubmitTx(context.Context, Tx) error
 }
+
+// The NonceClient interface is implemented by clients of chains where the
+// nonce of an account can be queried. This is needed to pick the nonce of the
+// next transaction from an account. It does not include the Client interface,
+// so that it can be implemented by clients that cannot submit transactions.
+type NonceClient interface {
+	// AccountNonce returns the nonce that should be used by the next
+	// transaction from the given address. This includes transactions that have
+	// been submitted, but not yet included in a block. If the nonce cannot be
+	// returned before the context is done, then an error should be returned.
+	AccountNonce(context.Context, address.Address) (pack.U256, error)
+}
+
+// The BalanceClient interface is implemented by clients of chains where the
+// balance of an account can be queried. It does not include the Client
+// interface, so that it can be implemented by clients that cannot submit
+// transactions.
+type BalanceClient interface {
+	// AccountBalance returns the balance of the given address, in the smallest
+	// unit of the native asset, as of the latest block. If the balance cannot
+	// be returned before the context is done, then an error should be
+	// returned.
+	AccountBalance(context.Context, address.Address) (pack.U256, error)
+}
@@ -0,0 +1,216 @@
+// Package nonce implements a Manager that picks the nonces of transactions sent
+// from an account. It can be used with any chain that implements both the
+// account.Client and account.NonceClient interfaces (for example,
+// ethereum.Client), and is safe to use from concurrent goroutines that are
+// building transactions from the same account.
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+// The Client interface defines the functionality required by a Manager. It
+// must be able to submit transactions, and report the pending nonce of an
+// account.
+type Client interface {
+	account.Client
+	account.NonceClient
+}
+
+// IsNonceTooLow returns true if the error was returned by a node because the
+// nonce of a transaction has already been used. Nodes do not agree on a
+// message, so the most common variations are checked.
+func IsNonceTooLow(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "nonce is too low")
+}
+
+// IsNonceUsed returns true if the error was returned by a node because a
+// transaction with the same nonce is already in its pool (either the same
+// transaction, or a different transaction that the node will not replace).
+// The nonce has been consumed, and must not be handed out again.
+func IsNonceUsed(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, substr := range []string{"already known", "known transaction", "replacement transaction underpriced"} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRejected returns true if the error was returned by a node because it
+// rejected a transaction while validating it, which means that the nonce of
+// the transaction has not been consumed. Errors that do not clearly come from
+// the node (for example, a context deadline, or a broken connection) are never
+// rejections, because the transaction might have reached the node.
+func IsRejected(err error) bool {
+	if err == nil || IsNonceTooLow(err) || IsNonceUsed(err) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, substr := range []string{
+		"insufficient funds",
+		"intrinsic gas too low",
+		"invalid sender",
+		"exceeds block gas limit",
+		"transaction underpriced",
+		"oversized data",
+		"negative value",
+		"exceeds the configured cap",
+	} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// A Manager hands out sequential nonces for transactions sent from one
+// account. It is seeded with the pending nonce of the account, and then counts
+// up without calling the client. Nonces of transactions that fail submission
+// can be released, and are handed out again before any new nonces, so that no
+// gaps are left in the sequence of nonces.
+type Manager struct {
+	client Client
+	addr   address.Address
+
+	mu        *sync.Mutex
+	seeded    bool
+	pending   *big.Int
+	next      *big.Int
+	reclaimed []*big.Int
+}
+
+// NewManager returns a Manager for the nonces of the given address. No call is
+// made to the client until the first nonce is needed.
+func NewManager(client Client, addr address.Address) *Manager {
+	return &Manager{
+		client: client,
+		addr:   addr,
+
+		mu:        new(sync.Mutex),
+		pending:   new(big.Int),
+		next:      new(big.Int),
+		reclaimed: []*big.Int{},
+	}
+}
+
+// Next returns the nonce that should be used by the next transaction. Released
+// nonces are returned first (lowest first), and then new nonces are returned
+// in sequence. The first call seeds the manager with the pending nonce of the
+// account.
+func (manager *Manager) Next(ctx context.Context) (pack.U256, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if !manager.seeded {
+		if err := manager.sync(ctx); err != nil {
+			return pack.U256{}, err
+		}
+	}
+	if len(manager.reclaimed) > 0 {
+		nonce := manager.reclaimed[0]
+		manager.reclaimed = manager.reclaimed[1:]
+		return pack.NewU256FromInt(nonce), nil
+	}
+	nonce := new(big.Int).Set(manager.next)
+	manager.next.Add(manager.next, big.NewInt(1))
+	return pack.NewU256FromInt(nonce), nil
+}
+
+// Release a nonce that was returned by Next, but will not be used (for
+// example, because the transaction failed submission). The nonce will be
+// returned again by Next. Releasing a nonce that was never returned, has
+// already been released, or is below the pending nonce of the account (as of
+// the last sync), does nothing.
+func (manager *Manager) Release(nonce pack.U256) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	n := nonce.Int()
+	if !manager.seeded || n.Cmp(manager.next) >= 0 || n.Cmp(manager.pending) < 0 {
+		return
+	}
+	i := sort.Search(len(manager.reclaimed), func(i int) bool {
+		return manager.reclaimed[i].Cmp(n) >= 0
+	})
+	if i < len(manager.reclaimed) && manager.reclaimed[i].Cmp(n) == 0 {
+		return
+	}
+	manager.reclaimed = append(manager.reclaimed, nil)
+	copy(manager.reclaimed[i+1:], manager.reclaimed[i:])
+	manager.reclaimed[i] = new(big.Int).Set(n)
+}
+
+// Resync the manager with the pending nonce of the account. This should be
+// called when the node reports that a nonce is too low, which usually means
+// that transactions have been sent from the account by someone else. Nonces
+// below the pending nonce are no longer handed out. Nonces that have been
+// handed out, but not yet submitted, are not handed out again.
+func (manager *Manager) Resync(ctx context.Context) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.sync(ctx)
+}
+
+// SubmitTx using the client. If the node rejects the transaction, then its
+// nonce is released so that it can be used again. If the node reports that
+// the nonce is too low, then the manager is resynced. If the node reports that
+// the nonce is already used by a transaction in its pool, then the nonce is
+// kept. Any other error is ambiguous (the transaction might have reached the
+// node), so the nonce is kept and the manager is resynced; the caller should
+// resubmit the transaction to find out whether it was accepted. The
+// transaction must have been built using a nonce returned by Next.
+func (manager *Manager) SubmitTx(ctx context.Context, tx account.Tx) error {
+	err := manager.client.SubmitTx(ctx, tx)
+	switch {
+	case err == nil:
+		return nil
+	case IsNonceUsed(err):
+		return err
+	case IsRejected(err):
+		manager.Release(tx.Nonce())
+		return err
+	}
+	if syncErr := manager.Resync(ctx); syncErr != nil {
+		return fmt.Errorf("%v: %v", err, syncErr)
+	}
+	return err
+}
+
+// sync the manager with the pending nonce of the account. The mutex must be
+// held by the caller.
+func (manager *Manager) sync(ctx context.Context) error {
+	pending, err := manager.client.AccountNonce(ctx, manager.addr)
+	if err != nil {
+		return fmt.Errorf("bad pending nonce: %v", err)
+	}
+	p := pending.Int()
+	manager.pending = new(big.Int).Set(p)
+	if !manager.seeded || p.Cmp(manager.next) > 0 {
+		manager.next = new(big.Int).Set(p)
+	}
+	i := sort.Search(len(manager.reclaimed), func(i int) bool {
+		return manager.reclaimed[i].Cmp(p) >= 0
+	})
+	manager.reclaimed = manager.reclaimed[i:]
+	manager.seeded = true
+	return nil
+}
@@ -0,0 +1,13 @@
+package nonce_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestNonce(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Nonce Suite")
+}
@@ -0,0 +1,227 @@
+package nonce_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/account/nonce"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mockTx is an account.Tx that only has a nonce.
+type mockTx struct {
+	nonce pack.U256
+}
+
+func (tx mockTx) Hash() pack.Bytes                      { return nil }
+func (tx mockTx) From() address.Address                 { return "" }
+func (tx mockTx) To() address.Address                   { return "" }
+func (tx mockTx) Value() pack.U256                      { return pack.NewU256FromU64(0) }
+func (tx mockTx) Nonce() pack.U256                      { return tx.nonce }
+func (tx mockTx) Payload() contract.CallData            { return nil }
+func (tx mockTx) Sighashes() ([]pack.Bytes32, error)    { return nil, nil }
+func (tx mockTx) Sign([]pack.Bytes65, pack.Bytes) error { return nil }
+func (tx mockTx) Serialize() (pack.Bytes, error)        { return nil, nil }
+
+// mockClient is a nonce.Client with a pending nonce that can be changed, and
+// that fails submissions with a given error.
+type mockClient struct {
+	mu        *sync.Mutex
+	pending   uint64
+	calls     int
+	submitErr error
+}
+
+func (client *mockClient) Tx(context.Context, pack.Bytes) (account.Tx, pack.U64, error) {
+	return nil, pack.NewU64(0), fmt.Errorf("not found")
+}
+
+func (client *mockClient) SubmitTx(context.Context, account.Tx) error {
+	return client.submitErr
+}
+
+func (client *mockClient) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.calls++
+	return pack.NewU256FromU64(pack.NewU64(client.pending)), nil
+}
+
+var _ = Describe("Nonce manager", func() {
+	addr := address.Address("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	u256 := func(x uint64) pack.U256 {
+		return pack.NewU256FromU64(pack.NewU64(x))
+	}
+	next := func(manager *nonce.Manager) pack.U256 {
+		n, err := manager.Next(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		return n
+	}
+
+	Context("when used by concurrent goroutines", func() {
+		It("should hand out unique and sequential nonces", func() {
+			client := &mockClient{mu: new(sync.Mutex), pending: 7}
+			manager := nonce.NewManager(client, addr)
+
+			n := 100
+			nonces := make(chan uint64, n)
+			wg := new(sync.WaitGroup)
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					nonce, err := manager.Next(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					nonces <- nonce.Int().Uint64()
+				}()
+			}
+			wg.Wait()
+			close(nonces)
+
+			seen := map[uint64]bool{}
+			for nonce := range nonces {
+				Expect(seen[nonce]).To(BeFalse())
+				seen[nonce] = true
+			}
+			for i := uint64(7); i < uint64(7+n); i++ {
+				Expect(seen[i]).To(BeTrue())
+			}
+			Expect(client.calls).To(Equal(1))
+		})
+	})
+
+	Context("when nonces are released", func() {
+		It("should hand them out again, lowest first", func() {
+			client := &mockClient{mu: new(sync.Mutex)}
+			manager := nonce.NewManager(client, addr)
+			for i := uint64(0); i < 5; i++ {
+				Expect(next(manager)).To(Equal(u256(i)))
+			}
+
+			manager.Release(u256(3))
+			manager.Release(u256(1))
+			manager.Release(u256(1))
+			// Nonces that were never handed out are ignored.
+			manager.Release(u256(10))
+
+			Expect(next(manager)).To(Equal(u256(1)))
+			Expect(next(manager)).To(Equal(u256(3)))
+			Expect(next(manager)).To(Equal(u256(5)))
+		})
+	})
+
+	Context("when submitting a transaction fails", func() {
+		It("should release the nonce", func() {
+			client := &mockClient{mu: new(sync.Mutex), submitErr: fmt.Errorf("insufficient funds")}
+			manager := nonce.NewManager(client, addr)
+			n := next(manager)
+			Expect(manager.SubmitTx(context.Background(), mockTx{nonce: n})).ToNot(Succeed())
+			Expect(next(manager)).To(Equal(n))
+		})
+
+		It("should resync when the nonce is too low", func() {
+			client := &mockClient{mu: new(sync.Mutex), pending: 2}
+			manager := nonce.NewManager(client, addr)
+			Expect(next(manager)).To(Equal(u256(2)))
+			Expect(next(manager)).To(Equal(u256(3)))
+			manager.Release(u256(2))
+
+			// Someone else sent transactions from the account.
+			client.pending = 6
+			client.submitErr = fmt.Errorf("nonce too low")
+			Expect(manager.SubmitTx(context.Background(), mockTx{nonce: u256(3)})).ToNot(Succeed())
+			Expect(next(manager)).To(Equal(u256(6)))
+			Expect(next(manager)).To(Equal(u256(7)))
+		})
+	})
+
+	Context("when releasing a nonce after a resync", func() {
+		It("should not hand out nonces below the pending nonce", func() {
+			client := &mockClient{mu: new(sync.Mutex), pending: 2}
+			manager := nonce.NewManager(client, addr)
+			Expect(next(manager)).To(Equal(u256(2)))
+			Expect(next(manager)).To(Equal(u256(3)))
+
+			// Another goroutine resyncs after the node has seen both
+			// transactions, and then a stale nonce is released.
+			client.pending = 4
+			Expect(manager.Resync(context.Background())).To(Succeed())
+			manager.Release(u256(3))
+			Expect(next(manager)).To(Equal(u256(4)))
+
+			// Nonces at, or above, the pending nonce can still be released.
+			manager.Release(u256(4))
+			Expect(next(manager)).To(Equal(u256(4)))
+		})
+	})
+
+	Context("when the result of submitting a transaction is ambiguous", func() {
+		It("should keep the nonce and resync", func() {
+			client := &mockClient{mu: new(sync.Mutex), pending: 2}
+			manager := nonce.NewManager(client, addr)
+			Expect(next(manager)).To(Equal(u256(2)))
+
+			client.submitErr = fmt.Errorf("bad \"eth_sendRawTransaction\": %v", context.DeadlineExceeded)
+			Expect(manager.SubmitTx(context.Background(), mockTx{nonce: u256(2)})).ToNot(Succeed())
+			Expect(client.calls).To(Equal(2))
+			Expect(next(manager)).To(Equal(u256(3)))
+		})
+	})
+
+	Context("when the nonce is already used by a transaction in the pool", func() {
+		It("should keep the nonce", func() {
+			for _, submitErr := range []error{
+				fmt.Errorf("already known"),
+				fmt.Errorf("replacement transaction underpriced"),
+			} {
+				client := &mockClient{mu: new(sync.Mutex), pending: 2, submitErr: submitErr}
+				manager := nonce.NewManager(client, addr)
+				Expect(next(manager)).To(Equal(u256(2)))
+				Expect(manager.SubmitTx(context.Background(), mockTx{nonce: u256(2)})).ToNot(Succeed())
+				Expect(next(manager)).To(Equal(u256(3)))
+			}
+		})
+	})
+
+	Context("when resyncing behind the local nonce", func() {
+		It("should not hand out nonces again", func() {
+			client := &mockClient{mu: new(sync.Mutex), pending: 0}
+			manager := nonce.NewManager(client, addr)
+			Expect(next(manager)).To(Equal(u256(0)))
+			Expect(next(manager)).To(Equal(u256(1)))
+
+			// The node has only seen the first transaction.
+			client.pending = 1
+			Expect(manager.Resync(context.Background())).To(Succeed())
+			Expect(next(manager)).To(Equal(u256(2)))
+		})
+	})
+
+	Context("when checking errors", func() {
+		It("should detect nonces that are too low", func() {
+			Expect(nonce.IsNonceTooLow(fmt.Errorf("bad \"eth_sendRawTransaction\": nonce too low"))).To(BeTrue())
+			Expect(nonce.IsNonceTooLow(fmt.Errorf("Nonce is too low"))).To(BeTrue())
+			Expect(nonce.IsNonceTooLow(fmt.Errorf("replacement transaction underpriced"))).To(BeFalse())
+			Expect(nonce.IsNonceTooLow(nil)).To(BeFalse())
+		})
+
+		It("should only detect rejections that come from the node", func() {
+			Expect(nonce.IsRejected(fmt.Errorf("bad \"eth_sendRawTransaction\": insufficient funds for gas * price + value"))).To(BeTrue())
+			Expect(nonce.IsRejected(fmt.Errorf("intrinsic gas too low"))).To(BeTrue())
+			Expect(nonce.IsRejected(fmt.Errorf("transaction underpriced"))).To(BeTrue())
+			Expect(nonce.IsRejected(fmt.Errorf("replacement transaction underpriced"))).To(BeFalse())
+			Expect(nonce.IsRejected(fmt.Errorf("nonce too low"))).To(BeFalse())
+			Expect(nonce.IsRejected(context.DeadlineExceeded)).To(BeFalse())
+			Expect(nonce.IsRejected(fmt.Errorf("dial tcp 127.0.0.1:8545: connection refused"))).To(BeFalse())
+			Expect(nonce.IsRejected(nil)).To(BeFalse())
+		})
+	})
+})
@@ -44,3 +44,65 @@ type Caller interface {
 	// error should be returned.
 	CallContract(context.Context, address.Address, CallData) (pack.Bytes, error)
 }
+
+// The CallerAt interface extends the Caller interface for chains where the
+// client can call contracts against the state of a historic block.
+type CallerAt interface {
+	Caller
+
+	// CallContractAt is the same as CallContract, but the call is executed
+	// against the state of the block at the given height. If the state of the
+	// block is not available (for example, because it has been pruned by the
+	// node), then an error should be returned.
+	CallContractAt(context.Context, address.Address, CallData, pack.U64) (pack.Bytes, error)
+}
+
+// A Log is emitted by a contract during the execution of a transaction. Logs
+// are used to report events (for example, the burning of tokens) to observers
+// outside of the chain.
+type Log struct {
+	// Address of the contract that emitted the log.
+	Address address.Address
+	// Topics are indexed values that can be used to filter logs. The first
+	// topic is usually the identifier of the event.
+	Topics []pack.Bytes32
+	// Data contains the values of the log that are not indexed.
+	Data pack.Bytes
+
+	// BlockNumber of the block in which the log was emitted.
+	BlockNumber pack.U64
+	// TxHash of the transaction that emitted the log.
+	TxHash pack.Bytes
+	// LogIndex of the log in the block.
+	LogIndex pack.U64
+}
+
+// A LogFilter is used to select logs from a range of blocks.
+type LogFilter struct {
+	// Addresses of the contracts that emitted the logs. If there are no
+	// addresses, then logs emitted by any contract are selected.
+	Addresses []address.Address
+	// Topics that the logs must have, by position. The log is selected if, at
+	// each position, its topic is one of the given topics. An empty set of
+	// topics selects any topic at that position.
+	Topics [][]pack.Bytes32
+	// FromBlock is the first block in the range (inclusive).
+	FromBlock pack.U64
+	// ToBlock is the last block in the range (inclusive). If ToBlock is zero,
+	// then the range has no last block: filtering selects logs up to the
+	// latest block, and streaming follows new blocks until it is stopped.
+	// Because of this, a range that ends at the genesis block cannot be
+	// expressed (but the genesis block has no logs).
+	ToBlock pack.U64
+}
+
+// The LogFilterer interface defines the functionality required to query the
+// logs emitted by contracts.
+type LogFilterer interface {
+	// FilterLogs returns the logs selected by the filter, in the order in which
+	// they were emitted. Implementations must support large ranges of blocks,
+	// even if the underlying node does not (by splitting the range into
+	// pages). If the logs cannot be returned before the context is done, then
+	// an error should be returned.
+	FilterLogs(context.Context, LogFilter) ([]Log, error)
+}
@@ -23,3 +23,19 @@ type Estimator interface {
 	// blocks happen a lot faster).
 	EstimateGasPrice(context.Context) (pack.U256, error)
 }
+
+// The DynamicFeeEstimator interface extends the Estimator interface for chains
+// with a fee market, where the price paid per unit of gas is made up of a base
+// fee (set by the protocol, and usually burned) and a priority tip (paid to the
+// block producer). For example, Ethereum after EIP-1559.
+type DynamicFeeEstimator interface {
+	Estimator
+
+	// EstimateGasFees returns the fee cap and the tip cap that should be used
+	// to get confirmation within a reasonable amount of time. The fee cap is
+	// the maximum total price-per-gas that the sender is willing to pay (base
+	// fee plus tip), and the tip cap is the maximum price-per-gas that the
+	// sender is willing to pay to the block producer. The fee cap is always
+	// greater than, or equal to, the tip cap.
+	EstimateGasFees(context.Context) (pack.U256, pack.U256, error)
+}
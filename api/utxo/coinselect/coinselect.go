@@ -0,0 +1,267 @@
+// Package coinselect implements strategies for choosing which outputs to spend
+// in order to fund a transaction. It can be used with any chain that uses
+// Bitcoin-style outputs (for example, the outputs returned by
+// bitcoin.Client.UnspentOutputs).
+package coinselect
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// MaxBranchAndBoundTries is the maximum number of branches that will be
+// explored by the BranchAndBound selector before it gives up.
+const MaxBranchAndBoundTries = 100000
+
+var (
+	// ErrInsufficientFunds is returned when the outputs cannot cover the
+	// target value and the fee.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrNoChangelessSolution is returned by the BranchAndBound selector when
+	// there is no selection of outputs that avoids creating change.
+	ErrNoChangelessSolution = errors.New("no changeless solution")
+)
+
+// Options are used to parameterise the behaviour of all selectors.
+type Options struct {
+	// FeeRate in SATs-per-byte.
+	FeeRate pack.U256
+	// DustLimit is the minimum value of a change output. Change that is less
+	// than the dust limit is paid as a fee.
+	DustLimit pack.U256
+	// BaseWeight of the transaction without any inputs. This includes the
+	// transaction overhead, and the outputs paying the recipients (but not the
+	// change output).
+	BaseWeight int
+	// ChangeWeight is the weight of the change output.
+	ChangeWeight int
+}
+
+// A Selector chooses which outputs to spend in order to fund a transaction that
+// pays the target value (the sum value of all recipients) plus the fee. Outputs
+// that cost more to spend than they are worth are never selected. Outputs with
+// P2SH or P2WSH pubkey scripts are not supported, because the size of the
+// inputs that spend them cannot be known, and are never selected.
+type Selector interface {
+	Select(outputs []utxo.Output, target pack.U256) ([]utxo.Input, error)
+}
+
+// A candidate is an output that can be selected, along with its value after
+// paying the fee required to spend it.
+type candidate struct {
+	output         utxo.Output
+	value          int64
+	effectiveValue int64
+}
+
+// BranchAndBound selects outputs that exactly fund the transaction without
+// creating change, allowing for overpayment of up to the cost of creating
+// change. This is the algorithm used by Bitcoin Core. If there is no such
+// selection, ErrNoChangelessSolution is returned and the caller should fall
+// back to another selector.
+type BranchAndBound struct {
+	opts Options
+}
+
+// NewBranchAndBound returns a selector that uses branch-and-bound.
+func NewBranchAndBound(opts Options) BranchAndBound {
+	return BranchAndBound{opts: opts}
+}
+
+// Select implements the Selector interface.
+func (selector BranchAndBound) Select(outputs []utxo.Output, target pack.U256) ([]utxo.Input, error) {
+	candidates, needed, err := prepare(outputs, target, selector.opts)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].effectiveValue > candidates[j].effectiveValue
+	})
+	costOfChange := fee(selector.opts.ChangeWeight, selector.opts.FeeRate.Int().Int64()) + selector.opts.DustLimit.Int().Int64()
+	upper := needed + costOfChange
+
+	remaining := int64(0)
+	for _, c := range candidates {
+		remaining += c.effectiveValue
+	}
+	if remaining < needed {
+		return nil, ErrInsufficientFunds
+	}
+
+	// Depth-first search over the inclusion (then exclusion) of each
+	// candidate, keeping the selection with the least excess.
+	var best []int
+	bestExcess := int64(-1)
+	selected := make([]int, 0, len(candidates))
+	tries := 0
+	var search func(i int, sum, remaining int64)
+	search = func(i int, sum, remaining int64) {
+		if tries >= MaxBranchAndBoundTries || sum > upper {
+			return
+		}
+		tries++
+		if sum >= needed {
+			if excess := sum - needed; bestExcess < 0 || excess < bestExcess {
+				best = append(best[:0], selected...)
+				bestExcess = excess
+			}
+			return
+		}
+		if i == len(candidates) || sum+remaining < needed {
+			return
+		}
+		selected = append(selected, i)
+		search(i+1, sum+candidates[i].effectiveValue, remaining-candidates[i].effectiveValue)
+		selected = selected[:len(selected)-1]
+		search(i+1, sum, remaining-candidates[i].effectiveValue)
+	}
+	search(0, 0, remaining)
+
+	if bestExcess < 0 {
+		return nil, ErrNoChangelessSolution
+	}
+	inputs := make([]utxo.Input, len(best))
+	for i, j := range best {
+		inputs[i] = utxo.Input{Output: candidates[j].output}
+	}
+	return inputs, nil
+}
+
+// LargestFirst selects the outputs with the largest values first. This
+// minimises the number of inputs (and so the fee), but consolidates the fewest
+// outputs.
+type LargestFirst struct {
+	opts Options
+}
+
+// NewLargestFirst returns a selector that selects the largest outputs first.
+func NewLargestFirst(opts Options) LargestFirst {
+	return LargestFirst{opts: opts}
+}
+
+// Select implements the Selector interface.
+func (selector LargestFirst) Select(outputs []utxo.Output, target pack.U256) ([]utxo.Input, error) {
+	candidates, needed, err := prepare(outputs, target, selector.opts)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].value > candidates[j].value
+	})
+	return accumulate(candidates, needed)
+}
+
+// SmallestFirst selects the outputs with the smallest values first. This
+// consolidates as many outputs as possible, at the cost of a higher fee.
+type SmallestFirst struct {
+	opts Options
+}
+
+// NewSmallestFirst returns a selector that selects the smallest outputs first.
+func NewSmallestFirst(opts Options) SmallestFirst {
+	return SmallestFirst{opts: opts}
+}
+
+// Select implements the Selector interface.
+func (selector SmallestFirst) Select(outputs []utxo.Output, target pack.U256) ([]utxo.Input, error) {
+	candidates, needed, err := prepare(outputs, target, selector.opts)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].value < candidates[j].value
+	})
+	return accumulate(candidates, needed)
+}
+
+// Random selects outputs in a random order. This avoids leaking information
+// about the wallet through the choice of outputs, which improves privacy.
+type Random struct {
+	opts Options
+	rng  *rand.Rand
+}
+
+// NewRandom returns a selector that selects outputs in a random order, using
+// the given source of randomness. The source is not safe for concurrent use, so
+// neither is the selector.
+func NewRandom(opts Options, rng *rand.Rand) Random {
+	return Random{opts: opts, rng: rng}
+}
+
+// Select implements the Selector interface.
+func (selector Random) Select(outputs []utxo.Output, target pack.U256) ([]utxo.Input, error) {
+	candidates, needed, err := prepare(outputs, target, selector.opts)
+	if err != nil {
+		return nil, err
+	}
+	selector.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return accumulate(candidates, needed)
+}
+
+// prepare the outputs for selection, returning the candidates that are worth
+// spending, and the total effective value that must be selected.
+func prepare(outputs []utxo.Output, target pack.U256, opts Options) ([]candidate, int64, error) {
+	feeRate := opts.FeeRate.Int()
+	if !feeRate.IsInt64() {
+		return nil, 0, fmt.Errorf("bad fee rate: %v", feeRate)
+	}
+	if !target.Int().IsInt64() {
+		return nil, 0, fmt.Errorf("bad target: %v", target)
+	}
+	if !opts.DustLimit.Int().IsInt64() {
+		return nil, 0, fmt.Errorf("bad dust limit: %v", opts.DustLimit)
+	}
+
+	candidates := make([]candidate, 0, len(outputs))
+	for i, output := range outputs {
+		weight, err := utxo.InputWeight(utxo.Input{Output: output})
+		if err != nil {
+			// The size of the input that spends the output cannot be known
+			// (for example, because it needs a redeem script).
+			continue
+		}
+		value := output.Value.Int()
+		if !value.IsInt64() {
+			return nil, 0, fmt.Errorf("bad output %v: value %v out of range", i, value)
+		}
+		effectiveValue := value.Int64() - fee(weight, feeRate.Int64())
+		if effectiveValue <= 0 {
+			// The output costs more to spend than it is worth.
+			continue
+		}
+		candidates = append(candidates, candidate{
+			output:         output,
+			value:          value.Int64(),
+			effectiveValue: effectiveValue,
+		})
+	}
+	return candidates, target.Int().Int64() + fee(opts.BaseWeight, feeRate.Int64()), nil
+}
+
+// accumulate candidates, in order, until their effective value is enough to
+// finish the transaction. Any excess is left for the caller to send to a change
+// output (or to pay as a fee, if the change would be dust).
+func accumulate(candidates []candidate, needed int64) ([]utxo.Input, error) {
+	inputs := []utxo.Input{}
+	sum := int64(0)
+	for _, c := range candidates {
+		inputs = append(inputs, utxo.Input{Output: c.output})
+		sum += c.effectiveValue
+		if sum >= needed {
+			return inputs, nil
+		}
+	}
+	return nil, ErrInsufficientFunds
+}
+
+// fee returns the fee for the given weight, at the given SATs-per-byte.
+func fee(weight int, feeRate int64) int64 {
+	return int64((weight+3)/4) * feeRate
+}
@@ -0,0 +1,13 @@
+package coinselect_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCoinselect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Coinselect Suite")
+}
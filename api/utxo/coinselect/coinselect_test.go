@@ -0,0 +1,141 @@
+package coinselect_test
+
+import (
+	"bytes"
+	"math/rand"
+
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/api/utxo/coinselect"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Coin selection", func() {
+	// P2WPKH pubkey script. Spending it costs 69 bytes (at 1 SAT-per-byte, the
+	// effective value of each output is its value minus 69).
+	pubKeyScript := pack.Bytes(append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0xff}, 20)...))
+	newOutput := func(i byte, value uint64) utxo.Output {
+		return utxo.Output{
+			Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{i}, 32)), Index: pack.NewU32(0)},
+			Value:        pack.NewU256FromU64(pack.NewU64(value)),
+			PubKeyScript: pubKeyScript,
+		}
+	}
+	outputs := []utxo.Output{
+		newOutput(1, 1069),
+		newOutput(2, 2069),
+		newOutput(3, 5069),
+		newOutput(4, 10069),
+		newOutput(5, 50), // Uneconomic
+	}
+	opts := coinselect.Options{
+		FeeRate:      pack.NewU256FromU64(pack.NewU64(1)),
+		DustLimit:    pack.NewU256FromU64(pack.NewU64(546)),
+		BaseWeight:   0,
+		ChangeWeight: 124,
+	}
+	target := func(value uint64) pack.U256 {
+		return pack.NewU256FromU64(pack.NewU64(value))
+	}
+	values := func(inputs []utxo.Input) []uint64 {
+		vs := make([]uint64, len(inputs))
+		for i := range inputs {
+			vs[i] = inputs[i].Value.Int().Uint64()
+		}
+		return vs
+	}
+
+	Context("when using branch-and-bound", func() {
+		It("should find an exact match", func() {
+			inputs, err := coinselect.NewBranchAndBound(opts).Select(outputs, target(7000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values(inputs)).To(ConsistOf(uint64(5069), uint64(2069)))
+		})
+
+		It("should find a match within the cost of change", func() {
+			inputs, err := coinselect.NewBranchAndBound(opts).Select(outputs, target(7500))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values(inputs)).To(ConsistOf(uint64(5069), uint64(2069), uint64(1069)))
+		})
+
+		It("should return an error when there is no changeless solution", func() {
+			_, err := coinselect.NewBranchAndBound(opts).Select(outputs, target(4000))
+			Expect(err).To(Equal(coinselect.ErrNoChangelessSolution))
+		})
+	})
+
+	Context("when selecting the largest outputs first", func() {
+		It("should select the fewest outputs", func() {
+			inputs, err := coinselect.NewLargestFirst(opts).Select(outputs, target(12000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values(inputs)).To(Equal([]uint64{10069, 5069}))
+		})
+	})
+
+	Context("when selecting the smallest outputs first", func() {
+		It("should select the most outputs, but not uneconomic outputs", func() {
+			inputs, err := coinselect.NewSmallestFirst(opts).Select(outputs, target(3500))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values(inputs)).To(Equal([]uint64{1069, 2069, 5069}))
+		})
+	})
+
+	Context("when selecting outputs randomly", func() {
+		It("should select enough outputs to cover the target", func() {
+			selector := coinselect.NewRandom(opts, rand.New(rand.NewSource(0)))
+			for i := 0; i < 100; i++ {
+				inputs, err := selector.Select(outputs, target(9000))
+				Expect(err).ToNot(HaveOccurred())
+				sum := uint64(0)
+				for _, v := range values(inputs) {
+					Expect(v).ToNot(Equal(uint64(50)))
+					sum += v - 69
+				}
+				Expect(sum).To(BeNumerically(">=", 9000))
+			}
+		})
+	})
+
+	Context("when the fee rate is taken into account", func() {
+		It("should select more outputs to cover the fee", func() {
+			opts := opts
+			opts.BaseWeight = 4 * 1000
+			inputs, err := coinselect.NewLargestFirst(opts).Select(outputs, target(10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values(inputs)).To(Equal([]uint64{10069, 5069}))
+		})
+	})
+
+	Context("when some outputs cannot be sized", func() {
+		It("should skip them", func() {
+			// A P2SH output needs a redeem script to be sized.
+			p2sh := newOutput(6, 100000)
+			p2sh.PubKeyScript = pack.Bytes(append(append([]byte{0xa9, 0x14}, bytes.Repeat([]byte{0xff}, 20)...), 0x87))
+			for _, selector := range []coinselect.Selector{
+				coinselect.NewLargestFirst(opts),
+				coinselect.NewSmallestFirst(opts),
+				coinselect.NewRandom(opts, rand.New(rand.NewSource(0))),
+			} {
+				inputs, err := selector.Select(append([]utxo.Output{p2sh}, outputs...), target(12000))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(values(inputs)).ToNot(ContainElement(uint64(100000)))
+			}
+		})
+	})
+
+	Context("when the outputs cannot cover the target", func() {
+		It("should return an error", func() {
+			for _, selector := range []coinselect.Selector{
+				coinselect.NewBranchAndBound(opts),
+				coinselect.NewLargestFirst(opts),
+				coinselect.NewSmallestFirst(opts),
+				coinselect.NewRandom(opts, rand.New(rand.NewSource(0))),
+			} {
+				_, err := selector.Select(outputs, target(20000))
+				Expect(err).To(Equal(coinselect.ErrInsufficientFunds))
+			}
+		})
+	})
+})
@@ -0,0 +1,290 @@
+package utxo
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/gas"
+	"github.com/renproject/pack"
+)
+
+// DefaultDustLimit is the minimum value of an output that will be relayed by
+// Bitcoin nodes (for P2PKH outputs at the default minimum relay fee). Change
+// that is less than the dust limit is not worth creating, and is paid as a fee
+// instead.
+const DefaultDustLimit = 546
+
+// A ScriptType identifies the standard form of a pubkey script, which
+// determines the size of the inputs that spend it.
+type ScriptType uint8
+
+// Enumerate the standard pubkey script types.
+const (
+	ScriptTypeUnknown = ScriptType(iota)
+	ScriptTypeP2PKH
+	ScriptTypeP2SH
+	ScriptTypeP2WPKH
+	ScriptTypeP2WSH
+	ScriptTypeP2TR
+)
+
+// String implements the Stringer interface.
+func (ty ScriptType) String() string {
+	switch ty {
+	case ScriptTypeP2PKH:
+		return "P2PKH"
+	case ScriptTypeP2SH:
+		return "P2SH"
+	case ScriptTypeP2WPKH:
+		return "P2WPKH"
+	case ScriptTypeP2WSH:
+		return "P2WSH"
+	case ScriptTypeP2TR:
+		return "P2TR"
+	default:
+		return "unknown"
+	}
+}
+
+// ScriptTypeOf returns the type of a pubkey script, or ScriptTypeUnknown if the
+// script is not one of the standard types.
+func ScriptTypeOf(script pack.Bytes) ScriptType {
+	switch {
+	case len(script) == 25 && script[0] == 0x76 && script[1] == 0xa9 && script[2] == 0x14 && script[23] == 0x88 && script[24] == 0xac:
+		// OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG
+		return ScriptTypeP2PKH
+	case len(script) == 23 && script[0] == 0xa9 && script[1] == 0x14 && script[22] == 0x87:
+		// OP_HASH160 <20 bytes> OP_EQUAL
+		return ScriptTypeP2SH
+	case len(script) == 22 && script[0] == 0x00 && script[1] == 0x14:
+		// OP_0 <20 bytes>
+		return ScriptTypeP2WPKH
+	case len(script) == 34 && script[0] == 0x00 && script[1] == 0x20:
+		// OP_0 <32 bytes>
+		return ScriptTypeP2WSH
+	case len(script) == 34 && script[0] == 0x51 && script[1] == 0x20:
+		// OP_1 <32 bytes>
+		return ScriptTypeP2TR
+	default:
+		return ScriptTypeUnknown
+	}
+}
+
+// The sizes (in bytes) of the parts of a signed transaction. Signatures are
+// assumed to be the maximum DER encoded length, and public keys are assumed to
+// be compressed. Inputs that spend P2SH or P2WSH outputs are assumed to need a
+// signature and a public key, unless the redeem script is a standard m-of-n
+// multisig script (in which case they need m signatures).
+const (
+	txOverheadSize     = 4 + 4 // Version and lock time
+	txWitnessFlag      = 2     // Segwit marker and flag (witness data)
+	txInSize           = 32 + 4 + 4
+	sigPushSize        = 1 + 72 + 1 // Push, DER signature, and sighash type
+	pubKeyPushSize     = 1 + 33
+	schnorrSigPushSize = 1 + 64
+	witnessScale       = 4
+	outputValueSize    = 8
+)
+
+// EstimateWeight returns the weight (as defined by BIP-141) of the transaction
+// that spends the given inputs, and produces outputs with the given pubkey
+// scripts, once it has been signed. The virtual size of the transaction is its
+// weight divided by four (rounded up). Inputs that spend P2SH or P2WSH outputs
+// must include the redeem script (or witness script) as their sig script.
+func EstimateWeight(inputs []Input, outputScripts []pack.Bytes) (int, error) {
+	weight := (txOverheadSize + varIntSize(len(inputs)) + varIntSize(len(outputScripts))) * witnessScale
+	hasWitness := false
+	for i, input := range inputs {
+		inputWeight, err := InputWeight(input)
+		if err != nil {
+			return 0, fmt.Errorf("bad input %v: %v", i, err)
+		}
+		switch ScriptTypeOf(input.PubKeyScript) {
+		case ScriptTypeP2WPKH, ScriptTypeP2WSH, ScriptTypeP2TR:
+			hasWitness = true
+		}
+		weight += inputWeight
+	}
+	if hasWitness {
+		// Inputs without witnesses still need to encode an empty witness.
+		for _, input := range inputs {
+			switch ScriptTypeOf(input.PubKeyScript) {
+			case ScriptTypeP2PKH, ScriptTypeP2SH:
+				weight += varIntSize(0)
+			}
+		}
+		weight += txWitnessFlag
+	}
+	for _, script := range outputScripts {
+		weight += OutputWeight(script)
+	}
+	return weight, nil
+}
+
+// InputWeight returns the weight (as defined by BIP-141) that the input adds to
+// a transaction once it has been signed. Inputs that spend P2SH or P2WSH outputs
+// must include the redeem script (or witness script) as their sig script.
+func InputWeight(input Input) (int, error) {
+	nonWitness, witness := txInSize, 0
+	switch ty := ScriptTypeOf(input.PubKeyScript); ty {
+	case ScriptTypeP2PKH:
+		nonWitness += varIntSize(sigPushSize+pubKeyPushSize) + sigPushSize + pubKeyPushSize
+	case ScriptTypeP2SH:
+		if len(input.SigScript) == 0 {
+			return 0, fmt.Errorf("expected redeem script")
+		}
+		scriptSigSize := sigPushSize + pubKeyPushSize + pushSize(len(input.SigScript)) + len(input.SigScript)
+		if threshold, ok := multisigThreshold(input.SigScript); ok {
+			// OP_0 <sig1> ... <sigm> <redeem script>
+			scriptSigSize = 1 + threshold*sigPushSize + pushSize(len(input.SigScript)) + len(input.SigScript)
+		}
+		nonWitness += varIntSize(scriptSigSize) + scriptSigSize
+	case ScriptTypeP2WPKH:
+		nonWitness += varIntSize(0)
+		witness = varIntSize(2) + sigPushSize + pubKeyPushSize
+	case ScriptTypeP2WSH:
+		if len(input.SigScript) == 0 {
+			return 0, fmt.Errorf("expected witness script")
+		}
+		nonWitness += varIntSize(0)
+		witness = varIntSize(3) + sigPushSize + pubKeyPushSize + varIntSize(len(input.SigScript)) + len(input.SigScript)
+		if threshold, ok := multisigThreshold(input.SigScript); ok {
+			// <> <sig1> ... <sigm> <witness script>
+			witness = varIntSize(threshold+2) + 1 + threshold*sigPushSize + varIntSize(len(input.SigScript)) + len(input.SigScript)
+		}
+	case ScriptTypeP2TR:
+		nonWitness += varIntSize(0)
+		witness = varIntSize(1) + schnorrSigPushSize
+	default:
+		return 0, fmt.Errorf("unsupported pubkey script %v", input.PubKeyScript)
+	}
+	return nonWitness*witnessScale + witness, nil
+}
+
+// OutputWeight returns the weight (as defined by BIP-141) that an output with
+// the given pubkey script adds to a transaction.
+func OutputWeight(script pack.Bytes) int {
+	return (outputValueSize + varIntSize(len(script)) + len(script)) * witnessScale
+}
+
+// Fund returns the recipients that should be passed to the transaction builder
+// so that the inputs pay the recipients, the fee, and the change. The fee is the
+// virtual size of the signed transaction multiplied by the gas price (in
+// SATs-per-byte) returned by the estimator. Change is sent to the given change
+// address, unless it would be less than the dust limit (in which case it is
+// paid as a fee). An error is returned if the inputs cannot cover the
+// recipients and the fee.
+//
+// The transaction builder is used to get the pubkey scripts of the recipients
+// (and the change address), so it must be the same builder that will be used
+// to build the funded transaction.
+func Fund(ctx context.Context, txBuilder TxBuilder, estimator gas.Estimator, inputs []Input, recipients []Recipient, change address.Address, dustLimit pack.U256) ([]Recipient, error) {
+	gasPrice, err := estimator.EstimateGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("estimating gas price: %v", err)
+	}
+
+	// Build the transaction with a change output, so that the pubkey scripts of
+	// all outputs are known.
+	withChange := make([]Recipient, len(recipients), len(recipients)+1)
+	copy(withChange, recipients)
+	withChange = append(withChange, Recipient{To: change, Value: pack.NewU256FromU64(pack.NewU64(0))})
+	tx, err := txBuilder.BuildTx(inputs, withChange)
+	if err != nil {
+		return nil, fmt.Errorf("building tx: %v", err)
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return nil, fmt.Errorf("bad outputs: %v", err)
+	}
+	outputScripts := make([]pack.Bytes, len(outputs))
+	for i := range outputs {
+		outputScripts[i] = outputs[i].PubKeyScript
+	}
+
+	inputValue := new(big.Int)
+	for _, input := range inputs {
+		inputValue.Add(inputValue, input.Value.Int())
+	}
+	outputValue := new(big.Int)
+	for _, recipient := range recipients {
+		outputValue.Add(outputValue, recipient.Value.Int())
+	}
+	fee := func(scripts []pack.Bytes) (*big.Int, error) {
+		weight, err := EstimateWeight(inputs, scripts)
+		if err != nil {
+			return nil, err
+		}
+		vsize := big.NewInt(int64((weight + witnessScale - 1) / witnessScale))
+		return vsize.Mul(vsize, gasPrice.Int()), nil
+	}
+
+	// Try to add change.
+	feeWithChange, err := fee(outputScripts)
+	if err != nil {
+		return nil, err
+	}
+	changeValue := new(big.Int).Sub(inputValue, outputValue)
+	changeValue.Sub(changeValue, feeWithChange)
+	if changeValue.Cmp(dustLimit.Int()) >= 0 {
+		withChange[len(recipients)].Value = pack.NewU256FromInt(changeValue)
+		return withChange, nil
+	}
+
+	// Otherwise, make sure that the inputs can cover the fee without change.
+	feeWithoutChange, err := fee(outputScripts[:len(recipients)])
+	if err != nil {
+		return nil, err
+	}
+	required := new(big.Int).Add(outputValue, feeWithoutChange)
+	if inputValue.Cmp(required) < 0 {
+		return nil, fmt.Errorf("insufficient funds: expected %v, got %v", required, inputValue)
+	}
+	return withChange[:len(recipients)], nil
+}
+
+// multisigThreshold returns the number of signatures required by a standard
+// m-of-n multisig script (OP_m <pubkey1> ... <pubkeyn> OP_n OP_CHECKMULTISIG),
+// and false if the script is not a multisig script.
+func multisigThreshold(script pack.Bytes) (int, bool) {
+	if len(script) < 3 || script[len(script)-1] != 0xae {
+		return 0, false
+	}
+	m, n := script[0], script[len(script)-2]
+	if m < 0x51 || m > 0x60 || n < m || n > 0x60 {
+		return 0, false
+	}
+	return int(m - 0x50), true
+}
+
+// varIntSize returns the number of bytes needed to encode the value as a
+// Bitcoin variable length integer.
+func varIntSize(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case uint64(n) <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
+
+// pushSize returns the number of bytes needed by the opcode that pushes data
+// of the given length onto the stack.
+func pushSize(n int) int {
+	switch {
+	case n < 0x4c:
+		return 1
+	case n <= 0xff:
+		return 2
+	case n <= 0xffff:
+		return 3
+	default:
+		return 5
+	}
+}
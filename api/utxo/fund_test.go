@@ -0,0 +1,158 @@
+package utxo_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Funding", func() {
+	params := &chaincfg.RegressionNetParams
+	txBuilder := bitcoin.NewTxBuilder(params)
+	gasEstimator := bitcoin.NewGasEstimator(pack.NewU256FromU64(pack.NewU64(10)))
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	Expect(err).ToNot(HaveOccurred())
+	pubKey := privKey.PubKey().SerializeCompressed()
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+	Expect(err).ToNot(HaveOccurred())
+	wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey), params)
+	Expect(err).ToNot(HaveOccurred())
+	pkhScript, err := txscript.PayToAddrScript(pkhAddr)
+	Expect(err).ToNot(HaveOccurred())
+	wpkhScript, err := txscript.PayToAddrScript(wpkhAddr)
+	Expect(err).ToNot(HaveOccurred())
+
+	newInput := func(b byte, script []byte, value uint64) utxo.Input {
+		return utxo.Input{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{b}, 32)), Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(script),
+				Value:        pack.NewU256FromU64(pack.NewU64(value)),
+			},
+		}
+	}
+	inputs := []utxo.Input{
+		newInput(0x01, pkhScript, 50000),
+		newInput(0x02, wpkhScript, 50000),
+	}
+	dustLimit := pack.NewU256FromU64(pack.NewU64(utxo.DefaultDustLimit))
+	change := address.Address(pkhAddr.EncodeAddress())
+
+	Context("when funding a transaction with change", func() {
+		It("should pay the estimated fee and send the rest to the change address", func() {
+			recipients := []utxo.Recipient{{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(60000))}}
+			funded, err := utxo.Fund(context.Background(), txBuilder, gasEstimator, inputs, recipients, change, dustLimit)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(funded).To(HaveLen(2))
+			Expect(funded[0]).To(Equal(recipients[0]))
+			Expect(funded[1].To).To(Equal(change))
+
+			// Sign the transaction, and check that the estimate is an upper
+			// bound on its actual virtual size.
+			tx, err := txBuilder.BuildTx(inputs, funded)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			signatures := make([]pack.Bytes65, len(sighashes))
+			for i := range sighashes {
+				signature, err := privKey.Sign(sighashes[i][:])
+				Expect(err).ToNot(HaveOccurred())
+				copy(signatures[i][:32], pack.NewU256FromInt(signature.R).Bytes())
+				copy(signatures[i][32:64], pack.NewU256FromInt(signature.S).Bytes())
+			}
+			Expect(tx.Sign(signatures, pack.NewBytes(pubKey))).To(Succeed())
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(bitcoin.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(serial))).To(Succeed())
+			actualWeight := msgTx.SerializeSizeStripped()*3 + msgTx.SerializeSize()
+
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			estimatedWeight, err := utxo.EstimateWeight(inputs, []pack.Bytes{outputs[0].PubKeyScript, outputs[1].PubKeyScript})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(estimatedWeight).To(BeNumerically(">=", actualWeight))
+			Expect(estimatedWeight).To(BeNumerically("<=", actualWeight+16))
+
+			fee := uint64(100000 - 60000 - funded[1].Value.Int().Uint64())
+			Expect(fee).To(Equal(uint64((estimatedWeight+3)/4) * 10))
+		})
+	})
+
+	Context("when the change would be dust", func() {
+		It("should not add change", func() {
+			recipients := []utxo.Recipient{{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(97000))}}
+			funded, err := utxo.Fund(context.Background(), txBuilder, gasEstimator, inputs, recipients, change, dustLimit)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(funded).To(Equal(recipients))
+		})
+	})
+
+	Context("when estimating the weight of inputs", func() {
+		It("should assume maximum length signatures and compressed keys", func() {
+			weight, err := utxo.InputWeight(inputs[0])
+			Expect(err).ToNot(HaveOccurred())
+			// The outpoint and sequence, and a 108 byte sig script.
+			Expect(weight).To(Equal((40 + 1 + 108) * 4))
+
+			weight, err = utxo.InputWeight(inputs[1])
+			Expect(err).ToNot(HaveOccurred())
+			// An empty sig script, and a witness with two items.
+			Expect(weight).To(Equal((40+1)*4 + 1 + 74 + 34))
+		})
+
+		It("should size multisig inputs from their redeem script", func() {
+			pubKeys := make([]*btcutil.AddressPubKey, 3)
+			for i := range pubKeys {
+				key, err := btcec.NewPrivateKey(btcec.S256())
+				Expect(err).ToNot(HaveOccurred())
+				pubKeys[i], err = btcutil.NewAddressPubKey(key.PubKey().SerializeCompressed(), params)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			redeemScript, err := txscript.MultiSigScript(pubKeys, 2)
+			Expect(err).ToNot(HaveOccurred())
+			scriptAddr, err := btcutil.NewAddressScriptHash(redeemScript, params)
+			Expect(err).ToNot(HaveOccurred())
+			script, err := txscript.PayToAddrScript(scriptAddr)
+			Expect(err).ToNot(HaveOccurred())
+
+			input := newInput(0x03, script, 50000)
+			_, err = utxo.InputWeight(input)
+			Expect(err).To(HaveOccurred())
+
+			// OP_0, two signatures, and the 105 byte redeem script.
+			input.SigScript = pack.Bytes(redeemScript)
+			weight, err := utxo.InputWeight(input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(weight).To(Equal((40 + 3 + 1 + 2*74 + 2 + 105) * 4))
+		})
+
+		It("should return an error for unsupported scripts", func() {
+			_, err := utxo.InputWeight(newInput(0x03, []byte{0x6a}, 50000))
+			Expect(err).To(HaveOccurred())
+			_, err = utxo.EstimateWeight([]utxo.Input{inputs[0], newInput(0x03, []byte{0x6a}, 50000)}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the inputs cannot cover the fee", func() {
+		It("should return an error", func() {
+			recipients := []utxo.Recipient{{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(99900))}}
+			_, err := utxo.Fund(context.Background(), txBuilder, gasEstimator, inputs, recipients, change, dustLimit)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
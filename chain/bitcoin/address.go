@@ -1,18 +1,43 @@
 package bitcoin
 
 import (
+	"fmt"
+
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
 	"github.com/renproject/multichain/api/address"
 	"github.com/renproject/pack"
 )
 
+const (
+	// Base58AddressLength is the length of a raw base58 address (P2PKH or
+	// P2SH). It is made up of a 1 byte version, a 20 byte hash, and a 4 byte
+	// checksum.
+	Base58AddressLength = 25
+	// WitnessPubKeyHashAddressLength is the length of a raw bech32 P2WPKH
+	// address. It is made up of a 1 byte witness version, and a 20 byte
+	// witness program.
+	WitnessPubKeyHashAddressLength = 21
+	// WitnessScriptHashAddressLength is the length of a raw bech32 P2WSH
+	// address. It is made up of a 1 byte witness version, and a 32 byte
+	// witness program.
+	WitnessScriptHashAddressLength = 33
+	// TaprootAddressLength is the length of a raw bech32m P2TR address. It is
+	// made up of a 1 byte witness version, and a 32 byte witness program (the
+	// output key).
+	TaprootAddressLength = 33
+)
+
+// AddressEncodeDecoder implements the address.EncodeDecoder interface.
 type AddressEncodeDecoder struct {
 	AddressEncoder
 	AddressDecoder
 }
 
+// NewAddressEncodeDecoder constructs a new AddressEncodeDecoder with the
+// chain specific configurations.
 func NewAddressEncodeDecoder(params *chaincfg.Params) AddressEncodeDecoder {
 	return AddressEncodeDecoder{
 		AddressEncoder: NewAddressEncoder(params),
@@ -20,15 +45,37 @@ func NewAddressEncodeDecoder(params *chaincfg.Params) AddressEncodeDecoder {
 	}
 }
 
+// AddressEncoder encapsulates the chain specific configurations and implements
+// the address.Encoder interface.
 type AddressEncoder struct {
 	params *chaincfg.Params
 }
 
+// NewAddressEncoder constructs a new AddressEncoder with the chain specific
+// configurations.
 func NewAddressEncoder(params *chaincfg.Params) AddressEncoder {
 	return AddressEncoder{params: params}
 }
 
+// EncodeAddress implements the address.Encoder interface. The kind of the
+// address is determined by the length of the raw address: raw base58 addresses
+// are encoded using base58, and raw witness addresses (the witness version
+// followed by the witness program) are encoded using bech32 (for version 0) or
+// bech32m (for version 1).
 func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	switch len(rawAddr) {
+	case Base58AddressLength:
+		return encoder.encodeBase58(rawAddr)
+	case WitnessPubKeyHashAddressLength, WitnessScriptHashAddressLength:
+		// Raw P2TR addresses have the same length as raw P2WSH addresses, and
+		// are distinguished by their witness version.
+		return encoder.encodeBech32(rawAddr)
+	default:
+		return address.Address(""), fmt.Errorf("non-exhaustive pattern: address length %v", len(rawAddr))
+	}
+}
+
+func (encoder AddressEncoder) encodeBase58(rawAddr address.RawAddress) (address.Address, error) {
 	encodedAddr := base58.Encode([]byte(rawAddr))
 	if _, err := btcutil.DecodeAddress(encodedAddr, encoder.params); err != nil {
 		// Check that the address is valid.
@@ -37,18 +84,61 @@ func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address
 	return address.Address(encodedAddr), nil
 }
 
+func (encoder AddressEncoder) encodeBech32(rawAddr address.RawAddress) (address.Address, error) {
+	program, err := bech32.ConvertBits([]byte(rawAddr[1:]), 8, 5, true)
+	if err != nil {
+		return address.Address(""), fmt.Errorf("bad witness program: %v", err)
+	}
+	encode := bech32.Encode
+	if rawAddr[0] != 0 {
+		encode = encodeBech32m
+	}
+	encodedAddr, err := encode(encoder.params.Bech32HRPSegwit, append([]byte{rawAddr[0]}, program...))
+	if err != nil {
+		return address.Address(""), fmt.Errorf("bad bech32 encoding: %v", err)
+	}
+	if _, err := decodeAddress(encodedAddr, encoder.params); err != nil {
+		// Check that the address is valid.
+		return address.Address(""), err
+	}
+	return address.Address(encodedAddr), nil
+}
+
+// AddressDecoder encapsulates the chain specific configurations and implements
+// the address.Decoder interface.
 type AddressDecoder struct {
 	params *chaincfg.Params
 }
 
+// NewAddressDecoder constructs a new AddressDecoder with the chain specific
+// configurations.
 func NewAddressDecoder(params *chaincfg.Params) AddressDecoder {
 	return AddressDecoder{params: params}
 }
 
-func (decoder AddressDecoder) DecodeAddress(addr address.Address) (pack.Bytes, error) {
-	if _, err := btcutil.DecodeAddress(string(addr), decoder.params); err != nil {
+// DecodeAddress implements the address.Decoder interface. Base58 addresses are
+// decoded into their version, hash, and checksum. Bech32 (and bech32m)
+// addresses are decoded into their witness version, and witness program.
+func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	decodedAddr, err := decodeAddress(string(addr), decoder.params)
+	if err != nil {
 		// Check that the address is valid.
 		return nil, err
 	}
-	return pack.NewBytes(base58.Decode(string(addr))), nil
+	if !decodedAddr.IsForNet(decoder.params) {
+		return nil, fmt.Errorf("address of different network")
+	}
+
+	switch a := decodedAddr.(type) {
+	case *btcutil.AddressPubKeyHash, *btcutil.AddressScriptHash:
+		return address.RawAddress(pack.NewBytes(base58.Decode(string(addr)))), nil
+	case *btcutil.AddressWitnessPubKeyHash:
+		return address.RawAddress(pack.NewBytes(append([]byte{a.WitnessVersion()}, a.WitnessProgram()...))), nil
+	case *btcutil.AddressWitnessScriptHash:
+		return address.RawAddress(pack.NewBytes(append([]byte{a.WitnessVersion()}, a.WitnessProgram()...))), nil
+	case *AddressTaproot:
+		return address.RawAddress(pack.NewBytes(append([]byte{a.WitnessVersion()}, a.WitnessProgram()...))), nil
+	default:
+		return nil, fmt.Errorf("non-exhaustive pattern: address %T", a)
+	}
 }
@@ -1 +1,55 @@
 package bitcoin_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/bitcoin"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Address", func() {
+	encodeDecoder := bitcoin.NewAddressEncodeDecoder(&chaincfg.MainNetParams)
+
+	DescribeTable("when decoding and encoding an address",
+		func(addr address.Address, expectedLen int) {
+			rawAddr, err := encodeDecoder.DecodeAddress(addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawAddr).To(HaveLen(expectedLen))
+
+			encodedAddr, err := encodeDecoder.EncodeAddress(rawAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encodedAddr).To(Equal(addr))
+		},
+
+		Entry("should round-trip P2PKH addresses", address.Address("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"), bitcoin.Base58AddressLength),
+		Entry("should round-trip P2SH addresses", address.Address("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"), bitcoin.Base58AddressLength),
+		Entry("should round-trip P2WPKH addresses", address.Address("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"), bitcoin.WitnessPubKeyHashAddressLength),
+		Entry("should round-trip P2WSH addresses", address.Address("bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"), bitcoin.WitnessScriptHashAddressLength),
+	)
+
+	Context("when decoding an address for a different network", func() {
+		It("should return an error", func() {
+			_, err := encodeDecoder.DecodeAddress(address.Address("tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when encoding a raw address of unknown length", func() {
+		It("should return an error", func() {
+			_, err := encodeDecoder.EncodeAddress(address.RawAddress(make([]byte, 20)))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when encoding a raw witness address with a bad witness version", func() {
+		It("should return an error", func() {
+			rawAddr := make([]byte, bitcoin.WitnessPubKeyHashAddressLength)
+			rawAddr[0] = 1
+			_, err := encodeDecoder.EncodeAddress(address.RawAddress(rawAddr))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
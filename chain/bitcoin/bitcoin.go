@@ -90,12 +90,40 @@ type Client interface {
 	Confirmations(ctx context.Context, txHash pack.Bytes) (int64, error)
 }
 
+// A FeeEstimatorClient can ask a Bitcoin node to estimate fee rates. It is
+// separate from the Client interface, so that adding fee estimation does not
+// break existing implementations of Client. The Client returned by NewClient
+// also implements FeeEstimatorClient.
+type FeeEstimatorClient interface {
+	// EstimateSmartFee returns the fee rate (in BTC/kB) that is needed for a
+	// transaction to be confirmed within the given number of blocks. It returns
+	// -1 if the node does not have enough data to make an estimate, and a
+	// *btcjson.RPCError if the node does not support "estimatesmartfee".
+	EstimateSmartFee(ctx context.Context, numBlocks int64) (float64, error)
+	// EstimateFee is the same as EstimateSmartFee, but uses the deprecated
+	// "estimatefee" method. It is used with forks that do not support the
+	// "estimatesmartfee" method.
+	EstimateFee(ctx context.Context, numBlocks int64) (float64, error)
+}
+
+// An RPCCaller can call any RPC method on a node. It is used by forks to call
+// methods that are not part of the Bitcoin RPC interface. The Client returned
+// by NewClient also implements RPCCaller.
+type RPCCaller interface {
+	// Call an RPC method on the node, and decode the result into resp.
+	// Requests that fail to reach the node are retried until the context is
+	// done, but errors returned by the node (as a *btcjson.RPCError) are
+	// returned immediately.
+	Call(ctx context.Context, resp interface{}, method string, params ...interface{}) error
+}
+
 type client struct {
 	opts       ClientOptions
 	httpClient http.Client
 }
 
-// NewClient returns a new Client.
+// NewClient returns a new Client. The Client also implements
+// FeeEstimatorClient and RPCCaller.
 func NewClient(opts ClientOptions) Client {
 	httpClient := http.Client{}
 	httpClient.Timeout = opts.Timeout
@@ -205,14 +233,71 @@ func (client *client) Confirmations(ctx context.Context, txHash pack.Bytes) (int
 	return confirmations, nil
 }
 
+// EstimateSmartFee returns the fee rate (in BTC/kB) that is needed for a
+// transaction to be confirmed within the given number of blocks.
+func (client *client) EstimateSmartFee(ctx context.Context, numBlocks int64) (float64, error) {
+	resp := struct {
+		FeeRate *float64 `json:"feerate"`
+		Blocks  int64    `json:"blocks"`
+	}{}
+	if err := client.sendWithRetry(ctx, &resp, "estimatesmartfee", []interface{}{numBlocks}, retryUnlessMethodNotFound); err != nil {
+		if _, ok := err.(*btcjson.RPCError); ok {
+			// Do not wrap the error, so that the caller can fall back to the
+			// "estimatefee" method.
+			return 0, err
+		}
+		return 0, fmt.Errorf("bad \"estimatesmartfee\": %v", err)
+	}
+	if resp.FeeRate == nil {
+		// The node does not have enough data to make an estimate.
+		return -1, nil
+	}
+	return *resp.FeeRate, nil
+}
+
+// EstimateFee returns the fee rate (in BTC/kB) that is needed for a transaction
+// to be confirmed within the given number of blocks.
+func (client *client) EstimateFee(ctx context.Context, numBlocks int64) (float64, error) {
+	resp := float64(0)
+	if err := client.sendWithRetry(ctx, &resp, "estimatefee", []interface{}{numBlocks}, retryUnlessMethodNotFound); err != nil {
+		return 0, fmt.Errorf("bad \"estimatefee\": %v", err)
+	}
+	return resp, nil
+}
+
+// Call an RPC method on the node, and decode the result into resp. Errors
+// returned by the node are not retried.
+func (client *client) Call(ctx context.Context, resp interface{}, method string, params ...interface{}) error {
+	// Nodes expect an empty list when there are no params.
+	if params == nil {
+		params = []interface{}{}
+	}
+	return client.sendWithRetry(ctx, resp, method, params, func(*btcjson.RPCError) bool { return false })
+}
+
 func (client *client) send(ctx context.Context, resp interface{}, method string, params ...interface{}) error {
+	return client.sendWithRetry(ctx, resp, method, params, func(*btcjson.RPCError) bool { return true })
+}
+
+// retryUnlessMethodNotFound is used for methods that are not supported by all
+// forks (for example, "estimatesmartfee"). Errors returned by the node because
+// the method does not exist will never succeed, so they are not retried, and
+// the caller can fall back to another method.
+func retryUnlessMethodNotFound(rpcErr *btcjson.RPCError) bool {
+	return rpcErr.Code != btcjson.ErrRPCMethodNotFound.Code
+}
+
+// sendWithRetry sends the request until it succeeds, or the context is done.
+// Errors returned by the node are only retried if shouldRetry returns true.
+func (client *client) sendWithRetry(ctx context.Context, resp interface{}, method string, params []interface{}, shouldRetry func(*btcjson.RPCError) bool) error {
 	// Encode the request.
 	data, err := encodeRequest(method, params)
 	if err != nil {
 		return err
 	}
 
-	return retry(ctx, client.opts.TimeoutRetry, func() error {
+	var rpcErr error
+	if err := retry(ctx, client.opts.TimeoutRetry, func() error {
 		// Create request and add basic authentication headers. The context is
 		// not attached to the request, and instead we all each attempt to run
 		// for the timeout duration, and we keep attempting until success, or
@@ -230,10 +315,17 @@ func (client *client) send(ctx context.Context, resp interface{}, method string,
 		}
 		defer res.Body.Close()
 		if err := decodeResponse(resp, res.Body); err != nil {
+			if e, ok := err.(*responseError); ok && e.rpcErr != nil && !shouldRetry(e.rpcErr) {
+				rpcErr = e.rpcErr
+				return nil
+			}
 			return fmt.Errorf("decoding http response: %v", err)
 		}
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+	return rpcErr
 }
 
 func encodeRequest(method string, params []interface{}) ([]byte, error) {
@@ -270,7 +362,12 @@ func decodeResponse(resp interface{}, r io.Reader) error {
 		return fmt.Errorf("decoding response: %v", err)
 	}
 	if res.Error != nil {
-		return fmt.Errorf("decoding response: %v", string(*res.Error))
+		respErr := &responseError{raw: string(*res.Error)}
+		rpcErr := btcjson.RPCError{}
+		if err := json.Unmarshal(*res.Error, &rpcErr); err == nil && rpcErr.Code != 0 {
+			respErr.rpcErr = &rpcErr
+		}
+		return respErr
 	}
 	if res.Result == nil {
 		return fmt.Errorf("decoding result: result is nil")
@@ -281,6 +378,18 @@ func decodeResponse(resp interface{}, r io.Reader) error {
 	return nil
 }
 
+// A responseError is returned when the node responds with an error. The error
+// returned by the node is kept, so that it can be returned as a
+// *btcjson.RPCError to callers that do not retry it.
+type responseError struct {
+	raw    string
+	rpcErr *btcjson.RPCError
+}
+
+func (err *responseError) Error() string {
+	return fmt.Sprintf("decoding response: %v", err.raw)
+}
+
 func retry(ctx context.Context, dur time.Duration, f func() error) error {
 	ticker := time.NewTicker(dur)
 	err := f()
@@ -0,0 +1,86 @@
+package bitcoin_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	// newStandIn returns a server that responds to every request using the
+	// given function, and counts the requests that it receives.
+	newStandIn := func(calls *int64, respond func(n int64, w http.ResponseWriter)) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respond(atomic.AddInt64(calls, 1), w)
+		}))
+	}
+	newClient := func(host string) bitcoin.Client {
+		opts := bitcoin.DefaultClientOptions().WithHost(host)
+		opts.TimeoutRetry = 10 * time.Millisecond
+		return bitcoin.NewClient(opts)
+	}
+	notFound := func(_ int64, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, `{"result":null,"error":{"code":-5,"message":"No such mempool or blockchain transaction"},"id":1}`)
+	}
+
+	Context("when the node returns an error", func() {
+		It("should retry requests until the context is done", func() {
+			calls := int64(0)
+			server := newStandIn(&calls, notFound)
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := newClient(server.URL).Confirmations(ctx, pack.Bytes(make([]byte, 32)))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(context.DeadlineExceeded.Error()))
+			Expect(atomic.LoadInt64(&calls)).To(BeNumerically(">", 1))
+		})
+
+		It("should return the error from Call without retrying", func() {
+			calls := int64(0)
+			server := newStandIn(&calls, notFound)
+			defer server.Close()
+
+			resp := json.RawMessage{}
+			err := newClient(server.URL).(bitcoin.RPCCaller).Call(context.Background(), &resp, "getrawtransaction", "00", 1)
+			Expect(err).To(HaveOccurred())
+			rpcErr, ok := err.(*btcjson.RPCError)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCInvalidAddressOrKey))
+			Expect(atomic.LoadInt64(&calls)).To(Equal(int64(1)))
+		})
+	})
+
+	Context("when the response cannot be decoded", func() {
+		It("should retry Call until it succeeds", func() {
+			calls := int64(0)
+			server := newStandIn(&calls, func(n int64, w http.ResponseWriter) {
+				if n < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+					fmt.Fprintf(w, "bad gateway")
+					return
+				}
+				fmt.Fprintf(w, `{"result":3,"error":null,"id":1}`)
+			})
+			defer server.Close()
+
+			resp := int64(0)
+			Expect(newClient(server.URL).(bitcoin.RPCCaller).Call(context.Background(), &resp, "getblockcount")).To(Succeed())
+			Expect(resp).To(Equal(int64(3)))
+			Expect(atomic.LoadInt64(&calls)).To(Equal(int64(3)))
+		})
+	})
+})
@@ -2,7 +2,11 @@ package bitcoin
 
 import (
 	"context"
+	"math"
+	"math/bits"
 
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcutil"
 	"github.com/renproject/pack"
 )
 
@@ -30,3 +34,66 @@ func NewGasEstimator(satsPerByte pack.U256) GasEstimator {
 func (gasEstimator GasEstimator) EstimateGasPrice(_ context.Context) (pack.U256, error) {
 	return gasEstimator.satsPerByte, nil
 }
+
+// A NodeGasEstimator returns the SATs-per-byte that is needed in order to
+// confirm transactions within a target number of blocks, as estimated by a
+// Bitcoin node. Nodes with slightly different views of the mempool will return
+// slightly different estimates, so the estimate is rounded up into buckets that
+// keep only the three most significant bits. This makes it likely that all
+// nodes in a distributed network reach consensus on the SATs-per-byte.
+type NodeGasEstimator struct {
+	client    FeeEstimatorClient
+	numBlocks int64
+	floor     pack.U256
+}
+
+// NewNodeGasEstimator returns a gas estimator that uses the "estimatesmartfee"
+// method of the node (or the "estimatefee" method, for forks that do not
+// support "estimatesmartfee") to target confirmation within the given number of
+// blocks. The given floor is returned when the node does not have enough data
+// to make an estimate, and is the minimum SATs-per-byte that will be returned.
+func NewNodeGasEstimator(client FeeEstimatorClient, numBlocks int64, floor pack.U256) NodeGasEstimator {
+	return NodeGasEstimator{
+		client:    client,
+		numBlocks: numBlocks,
+		floor:     floor,
+	}
+}
+
+// EstimateGasPrice returns the number of SATs-per-byte that is needed in order
+// to confirm transactions within the target number of blocks. It is the
+// responsibility of the caller to know the number of bytes in their
+// transaction.
+func (gasEstimator NodeGasEstimator) EstimateGasPrice(ctx context.Context) (pack.U256, error) {
+	btcPerKB, err := gasEstimator.client.EstimateSmartFee(ctx, gasEstimator.numBlocks)
+	if err != nil {
+		if _, ok := err.(*btcjson.RPCError); !ok {
+			return pack.U256{}, err
+		}
+		// The node does not support "estimatesmartfee".
+		if btcPerKB, err = gasEstimator.client.EstimateFee(ctx, gasEstimator.numBlocks); err != nil {
+			return pack.U256{}, err
+		}
+	}
+	if btcPerKB <= 0 {
+		return gasEstimator.floor, nil
+	}
+
+	// Convert from BTC/kB to SATs-per-byte, rounding up.
+	satsPerKB := uint64(math.Round(btcPerKB * btcutil.SatoshiPerBitcoin))
+	satsPerByte := pack.NewU256FromU64(pack.NewU64(roundUpToBucket((satsPerKB + 999) / 1000)))
+	if satsPerByte.Int().Cmp(gasEstimator.floor.Int()) < 0 {
+		return gasEstimator.floor, nil
+	}
+	return satsPerByte, nil
+}
+
+// roundUpToBucket rounds the value up so that it only has three significant
+// bits (for example, 9 is rounded up to 10, and 100 is rounded up to 112).
+func roundUpToBucket(x uint64) uint64 {
+	if x <= 8 {
+		return x
+	}
+	mask := uint64(1)<<uint(bits.Len64(x)-3) - 1
+	return (x + mask) &^ mask
+}
@@ -1 +1,70 @@
 package bitcoin_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gas", func() {
+	// newStandIn returns a server that responds to JSON-RPC requests using the
+	// given results. Methods without a result are not found.
+	newStandIn := func(results map[string]interface{}) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := struct {
+				Method string `json:"method"`
+			}{}
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+			result, ok := results[req.Method]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":1}`)
+				return
+			}
+			Expect(json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil, "id": 1})).To(Succeed())
+		}))
+	}
+	floor := pack.NewU256FromU64(pack.NewU64(2))
+
+	DescribeTable("when estimating the gas price using the node",
+		func(results map[string]interface{}, expected uint64) {
+			server := newStandIn(results)
+			defer server.Close()
+
+			client := bitcoin.NewClient(bitcoin.DefaultClientOptions().WithHost(server.URL)).(bitcoin.FeeEstimatorClient)
+			gasEstimator := bitcoin.NewNodeGasEstimator(client, 2, floor)
+			satsPerByte, err := gasEstimator.EstimateGasPrice(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(satsPerByte).To(Equal(pack.NewU256FromU64(pack.NewU64(expected))))
+		},
+
+		Entry("should convert BTC/kB to SATs-per-byte", map[string]interface{}{"estimatesmartfee": map[string]interface{}{"feerate": 0.00005, "blocks": 2}}, uint64(5)),
+		Entry("should round up to the nearest bucket", map[string]interface{}{"estimatesmartfee": map[string]interface{}{"feerate": 0.000999, "blocks": 2}}, uint64(112)),
+		Entry("should return the same bucket for similar estimates", map[string]interface{}{"estimatesmartfee": map[string]interface{}{"feerate": 0.00105, "blocks": 2}}, uint64(112)),
+		Entry("should fall back to estimatefee", map[string]interface{}{"estimatefee": 0.00005}, uint64(5)),
+		Entry("should return the floor when the node has no estimate", map[string]interface{}{"estimatesmartfee": map[string]interface{}{"errors": []string{"Insufficient data or no feerate found"}, "blocks": 0}}, uint64(2)),
+		Entry("should return the floor when the legacy estimate is unavailable", map[string]interface{}{"estimatefee": -1}, uint64(2)),
+		Entry("should return the floor when the estimate is below it", map[string]interface{}{"estimatesmartfee": map[string]interface{}{"feerate": 0.00001, "blocks": 2}}, uint64(2)),
+	)
+
+	Context("when the node supports neither fee estimation method", func() {
+		It("should return an error", func() {
+			server := newStandIn(map[string]interface{}{})
+			defer server.Close()
+
+			client := bitcoin.NewClient(bitcoin.DefaultClientOptions().WithHost(server.URL)).(bitcoin.FeeEstimatorClient)
+			gasEstimator := bitcoin.NewNodeGasEstimator(client, 2, floor)
+			_, err := gasEstimator.EstimateGasPrice(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,276 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/renproject/multichain/api/utxo"
+)
+
+// TaprootWitnessVersion is the witness version used by P2TR outputs.
+const TaprootWitnessVersion = 1
+
+// SigHashDefault is the BIP-341 sighash type used for key-path spends. It
+// commits to the same data as SIGHASH_ALL, but is omitted from the signature.
+const SigHashDefault = 0x00
+
+// AddressTaproot is a pay-to-taproot (P2TR) address. It implements the
+// btcutil.Address interface, which does not support witness version 1 in the
+// version of btcutil used by the multichain.
+type AddressTaproot struct {
+	hrp            string
+	witnessProgram [32]byte
+}
+
+// NewAddressTaproot returns a new P2TR address for the given 32 byte output
+// key (the witness program).
+func NewAddressTaproot(witnessProgram []byte, params *chaincfg.Params) (*AddressTaproot, error) {
+	if len(witnessProgram) != 32 {
+		return nil, fmt.Errorf("expected witness program of length 32, got length %v", len(witnessProgram))
+	}
+	addr := &AddressTaproot{hrp: strings.ToLower(params.Bech32HRPSegwit)}
+	copy(addr.witnessProgram[:], witnessProgram)
+	return addr, nil
+}
+
+// EncodeAddress returns the bech32m encoding of the address.
+func (addr *AddressTaproot) EncodeAddress() string {
+	program, err := bech32.ConvertBits(addr.witnessProgram[:], 8, 5, true)
+	if err != nil {
+		return ""
+	}
+	encoded, err := encodeBech32m(addr.hrp, append([]byte{TaprootWitnessVersion}, program...))
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
+
+// ScriptAddress returns the witness program of the address.
+func (addr *AddressTaproot) ScriptAddress() []byte {
+	return addr.witnessProgram[:]
+}
+
+// IsForNet returns whether or not the address is associated with the given
+// network.
+func (addr *AddressTaproot) IsForNet(params *chaincfg.Params) bool {
+	return addr.hrp == strings.ToLower(params.Bech32HRPSegwit)
+}
+
+// String returns the bech32m encoding of the address.
+func (addr *AddressTaproot) String() string {
+	return addr.EncodeAddress()
+}
+
+// WitnessVersion returns the witness version of the address.
+func (addr *AddressTaproot) WitnessVersion() byte {
+	return TaprootWitnessVersion
+}
+
+// WitnessProgram returns the witness program of the address.
+func (addr *AddressTaproot) WitnessProgram() []byte {
+	return addr.witnessProgram[:]
+}
+
+// DecodeTaprootAddress decodes a bech32m encoded P2TR address for the given
+// network.
+func DecodeTaprootAddress(addr string, params *chaincfg.Params) (*AddressTaproot, error) {
+	hrp, data, err := decodeBech32m(addr)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != strings.ToLower(params.Bech32HRPSegwit) {
+		return nil, fmt.Errorf("address of different network: expected %v, got %v", params.Bech32HRPSegwit, hrp)
+	}
+	if len(data) < 1 || data[0] != TaprootWitnessVersion {
+		return nil, fmt.Errorf("unsupported witness version")
+	}
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("bad witness program: %v", err)
+	}
+	return NewAddressTaproot(program, params)
+}
+
+// IsPayToTaproot returns true if the script is a P2TR pubkey script.
+func IsPayToTaproot(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == txscript.OP_DATA_32
+}
+
+// decodeAddress using btcutil, falling back to P2TR addresses (which are not
+// supported by btcutil).
+func decodeAddress(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	decodedAddr, err := btcutil.DecodeAddress(addr, params)
+	if err == nil {
+		return decodedAddr, nil
+	}
+	if taprootAddr, taprootErr := DecodeTaprootAddress(addr, params); taprootErr == nil {
+		return taprootAddr, nil
+	}
+	return nil, err
+}
+
+// payToAddrScript returns the pubkey script that pays to the given address.
+func payToAddrScript(addr btcutil.Address) ([]byte, error) {
+	if taprootAddr, ok := addr.(*AddressTaproot); ok {
+		return txscript.NewScriptBuilder().
+			AddOp(txscript.OP_1).
+			AddData(taprootAddr.WitnessProgram()).
+			Script()
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// calcTaprootSigHash returns the BIP-341 sighash for a key-path spend of the
+// input at the given index, using SIGHASH_DEFAULT and no annex. The inputs must
+// include the pubkey script and value of every output being spent.
+//
+// https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#common-signature-message
+func calcTaprootSigHash(msgTx *wire.MsgTx, inputs []utxo.Input, idx int) ([]byte, error) {
+	prevouts := new(bytes.Buffer)
+	amounts := new(bytes.Buffer)
+	pubKeyScripts := new(bytes.Buffer)
+	sequences := new(bytes.Buffer)
+	for i, txin := range msgTx.TxIn {
+		value := inputs[i].Value.Int()
+		if value.Sign() < 0 || !value.IsInt64() {
+			return nil, fmt.Errorf("bad input %v: value %v out of range", i, value)
+		}
+		prevouts.Write(txin.PreviousOutPoint.Hash[:])
+		binary.Write(prevouts, binary.LittleEndian, txin.PreviousOutPoint.Index)
+		binary.Write(amounts, binary.LittleEndian, value.Int64())
+		if err := wire.WriteVarBytes(pubKeyScripts, 0, inputs[i].PubKeyScript); err != nil {
+			return nil, err
+		}
+		binary.Write(sequences, binary.LittleEndian, txin.Sequence)
+	}
+	outputs := new(bytes.Buffer)
+	for _, txout := range msgTx.TxOut {
+		if err := wire.WriteTxOut(outputs, 0, msgTx.Version, txout); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := new(bytes.Buffer)
+	msg.WriteByte(0x00) // Epoch
+	msg.WriteByte(SigHashDefault)
+	binary.Write(msg, binary.LittleEndian, msgTx.Version)
+	binary.Write(msg, binary.LittleEndian, msgTx.LockTime)
+	for _, buf := range []*bytes.Buffer{prevouts, amounts, pubKeyScripts, sequences, outputs} {
+		hash := sha256.Sum256(buf.Bytes())
+		msg.Write(hash[:])
+	}
+	msg.WriteByte(0x00) // Spend type: key-path, no annex
+	binary.Write(msg, binary.LittleEndian, uint32(idx))
+
+	return taggedHash("TapSighash", msg.Bytes()), nil
+}
+
+// taggedHash as defined by BIP-340.
+func taggedHash(tag string, msg []byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// bech32Charset is the alphabet used by bech32 and bech32m.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32mConst is XORed into the checksum of bech32m strings (bech32 uses 1).
+//
+// https://github.com/bitcoin/bips/blob/master/bip-0350.mediawiki
+const bech32mConst = 0x2bc830a3
+
+// encodeBech32m the 5-bit data groups with the given human-readable part.
+func encodeBech32m(hrp string, data []byte) (string, error) {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32mConst
+
+	encoded := strings.Builder{}
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, d := range data {
+		if d >= 32 {
+			return "", fmt.Errorf("invalid data byte %v", d)
+		}
+		encoded.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		encoded.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&0x1f])
+	}
+	return encoded.String(), nil
+}
+
+// decodeBech32m into its human-readable part and 5-bit data groups (excluding
+// the checksum).
+func decodeBech32m(encoded string) (string, []byte, error) {
+	if len(encoded) > 90 {
+		return "", nil, fmt.Errorf("invalid length %v", len(encoded))
+	}
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, fmt.Errorf("mixed case")
+	}
+	encoded = strings.ToLower(encoded)
+	sep := strings.LastIndexByte(encoded, '1')
+	if sep < 1 || sep+7 > len(encoded) {
+		return "", nil, fmt.Errorf("invalid separator index %v", sep)
+	}
+	hrp := encoded[:sep]
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			return "", nil, fmt.Errorf("invalid character %q", c)
+		}
+	}
+	data := make([]byte, 0, len(encoded)-sep-1)
+	for _, c := range encoded[sep+1:] {
+		d := strings.IndexRune(bech32Charset, c)
+		if d < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", c)
+		}
+		data = append(data, byte(d))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), data...)) != bech32mConst {
+		return "", nil, fmt.Errorf("invalid checksum")
+	}
+	return hrp, data[:len(data)-6], nil
+}
+
+// bech32HRPExpand expands the human-readable part for use in the checksum.
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&0x1f)
+	}
+	return expanded
+}
+
+// bech32Polymod is the BCH checksum shared by bech32 and bech32m.
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,117 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Taproot", func() {
+	// The vectors below were generated by btcd v0.24, using the BIP-86 output
+	// key for the private key 0x0101...01.
+	taprootAddr := address.Address("bc1p33wm0auhr9kkahzd6l0kqj85af4cswn276hsxg6zpz85xe2r0y8syx4e5t")
+	taprootScript := mustDecodeHex("51208c5db7f797196d6edc4dd7df6048f4ea6b883a6af6af032342088f436543790f")
+	witnessPubKeyHashScript := mustDecodeHex("001479b000887626b294a914501a4cd226b58b235983")
+	expectedSighash := mustDecodeHex("7287d67aa8d58e791d8a8f7db4451200f61e579c9a84a5f153d3cf8315114fb0")
+	signature := mustDecodeHex("69197905a793bedb5ea4970b035e4298e4cddde510bcb0b068cb62e06457699ce9073dd926a9486dc0aca89b98cb5b2ec6815c9a0f6fabde3d4db05b41b93354")
+
+	inputs := []utxo.Input{
+		{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x11}, 32)), Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(taprootScript),
+				Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+			},
+		},
+		{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x22}, 32)), Index: pack.NewU32(1)},
+				PubKeyScript: pack.Bytes(witnessPubKeyHashScript),
+				Value:        pack.NewU256FromU64(pack.NewU64(50000)),
+			},
+		},
+	}
+	recipients := []utxo.Recipient{
+		{
+			To:    taprootAddr,
+			Value: pack.NewU256FromU64(pack.NewU64(120000)),
+		},
+	}
+
+	Context("when encoding and decoding a P2TR address", func() {
+		It("should round-trip using bech32m", func() {
+			encodeDecoder := bitcoin.NewAddressEncodeDecoder(&chaincfg.MainNetParams)
+			rawAddr, err := encodeDecoder.DecodeAddress(taprootAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawAddr).To(HaveLen(bitcoin.TaprootAddressLength))
+			Expect([]byte(rawAddr)).To(Equal(append([]byte{bitcoin.TaprootWitnessVersion}, taprootScript[2:]...)))
+
+			encodedAddr, err := encodeDecoder.EncodeAddress(rawAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encodedAddr).To(Equal(taprootAddr))
+		})
+	})
+
+	Context("when decoding a P2TR address with a bech32 checksum", func() {
+		It("should return an error", func() {
+			// BIP-350 test vector: witness version 1 with a bech32 checksum.
+			_, err := bitcoin.DecodeTaprootAddress("bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7k7grplx", &chaincfg.MainNetParams)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building a transaction that pays to a P2TR address", func() {
+		It("should use a P2TR pubkey script", func() {
+			tx, err := bitcoin.NewTxBuilder(&chaincfg.MainNetParams).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(1))
+			Expect([]byte(outputs[0].PubKeyScript)).To(Equal(taprootScript))
+			Expect(bitcoin.IsPayToTaproot(outputs[0].PubKeyScript)).To(BeTrue())
+		})
+	})
+
+	Context("when spending a P2TR output using the key path", func() {
+		It("should return the BIP-341 sighash and produce a witness", func() {
+			tx, err := bitcoin.NewTxBuilder(&chaincfg.MainNetParams).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes).To(HaveLen(2))
+			Expect(sighashes[0][:]).To(Equal(expectedSighash))
+
+			schnorrSig := pack.Bytes65{}
+			copy(schnorrSig[:], signature)
+			ecdsaSig := pack.Bytes65{}
+			ecdsaSig[31], ecdsaSig[63] = 1, 1
+			pubKey := pack.Bytes(make([]byte, 33))
+			Expect(tx.Sign([]pack.Bytes65{schnorrSig, ecdsaSig}, pubKey)).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(bitcoin.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(serial))).To(Succeed())
+			Expect(msgTx.TxIn[0].SignatureScript).To(BeEmpty())
+			Expect(msgTx.TxIn[0].Witness).To(Equal(wire.TxWitness{signature}))
+			Expect(msgTx.TxIn[1].Witness).To(HaveLen(2))
+		})
+	})
+})
+
+func mustDecodeHex(str string) []byte {
+	data, err := hex.DecodeString(str)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/btcsuite/btcutil"
 	"github.com/renproject/multichain/api/utxo"
 	"github.com/renproject/pack"
 )
@@ -36,9 +35,9 @@ func NewTxBuilder(params *chaincfg.Params) TxBuilder {
 // inputs, and sends them to the given recipients. The difference in the sum
 // value of the inputs and the sum value of the recipients is paid as a fee to
 // the Bitcoin network. This fee must be calculated independently of this
-// function. Outputs produced for recipients will use P2PKH, P2SH, P2WPKH, or
-// P2WSH scripts as the pubkey script, based on the format of the recipient
-// address.
+// function. Outputs produced for recipients will use P2PKH, P2SH, P2WPKH,
+// P2WSH, or P2TR scripts as the pubkey script, based on the format of the
+// recipient address.
 func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error) {
 	msgTx := wire.NewMsgTx(Version)
 
@@ -52,11 +51,11 @@ func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipi
 
 	// Outputs
 	for _, recipient := range recipients {
-		addr, err := btcutil.DecodeAddress(string(recipient.To), txBuilder.params)
+		addr, err := decodeAddress(string(recipient.To), txBuilder.params)
 		if err != nil {
 			return nil, err
 		}
-		script, err := txscript.PayToAddrScript(addr)
+		script, err := payToAddrScript(addr)
 		if err != nil {
 			return nil, err
 		}
@@ -126,7 +125,9 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 		var hash []byte
 		var err error
 		if sigScript == nil {
-			if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) {
+			if IsPayToTaproot(pubKeyScript) {
+				hash, err = calcTaprootSigHash(tx.msgTx, tx.inputs, i)
+			} else if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) {
 				hash, err = txscript.CalcWitnessSigHash(pubKeyScript, txscript.NewTxSigHashes(tx.msgTx), txscript.SigHashAll, tx.msgTx, i, value)
 			} else {
 				hash, err = txscript.CalcSignatureHash(pubKeyScript, txscript.SigHashAll, tx.msgTx, i)
@@ -150,6 +151,10 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 	return sighashes, nil
 }
 
+// Sign the transaction by injecting signatures into the inputs. Signatures for
+// P2TR inputs must be 64 byte BIP-340 Schnorr signatures over the key-path
+// sighash (the last byte of the signature is ignored). All other signatures
+// must be ECDSA signatures.
 func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	if tx.signed {
 		return fmt.Errorf("already signed")
@@ -171,6 +176,12 @@ func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 		pubKeyScript := tx.inputs[i].Output.PubKeyScript
 		sigScript := tx.inputs[i].SigScript
 
+		// Support taproot key-path spends.
+		if sigScript == nil && IsPayToTaproot(pubKeyScript) {
+			tx.msgTx.TxIn[i].Witness = wire.TxWitness([][]byte{append([]byte{}, rsv[:64]...)})
+			continue
+		}
+
 		// Support segwit.
 		if sigScript == nil {
 			if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) || txscript.IsPayToWitnessScriptHash(pubKeyScript) {
@@ -1 +0,0 @@
-package bitcoin_test
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/renproject/multichain/api/address"
 )
 
 type Address interface {
@@ -124,3 +128,107 @@ func (address AddressPubKeyHash) ScriptAddress() []byte {
 func (address AddressScriptHash) ScriptAddress() []byte {
 	return address.AddressScriptHash.ScriptAddress()
 }
+
+// AddressEncodeDecoder implements the address.EncodeDecoder interface.
+type AddressEncodeDecoder struct {
+	AddressEncoder
+	AddressDecoder
+}
+
+// NewAddressEncodeDecoder constructs a new AddressEncodeDecoder with the
+// chain specific configurations.
+func NewAddressEncodeDecoder(params *ChainParams) AddressEncodeDecoder {
+	return AddressEncodeDecoder{
+		AddressEncoder: NewAddressEncoder(params),
+		AddressDecoder: NewAddressDecoder(params),
+	}
+}
+
+// AddressEncoder encapsulates the chain specific configurations and implements
+// the address.Encoder interface.
+type AddressEncoder struct {
+	params *ChainParams
+}
+
+// NewAddressEncoder constructs a new AddressEncoder with the chain specific
+// configurations.
+func NewAddressEncoder(params *ChainParams) AddressEncoder {
+	return AddressEncoder{params: params}
+}
+
+// EncodeAddress implements the address.Encoder interface. The raw address is
+// the prefix, the hash, and the checksum.
+func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	if err := verifyRawAddress(rawAddr, encoder.params); err != nil {
+		return address.Address(""), err
+	}
+	return address.Address(base58.Encode(rawAddr)), nil
+}
+
+// AddressDecoder encapsulates the chain specific configurations and implements
+// the address.Decoder interface.
+type AddressDecoder struct {
+	params *ChainParams
+}
+
+// NewAddressDecoder constructs a new AddressDecoder with the chain specific
+// configurations.
+func NewAddressDecoder(params *ChainParams) AddressDecoder {
+	return AddressDecoder{params: params}
+}
+
+// DecodeAddress implements the address.Decoder interface. The address is
+// decoded into its prefix, hash, and checksum.
+func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	rawAddr := base58.Decode(string(addr))
+	if err := verifyRawAddress(rawAddr, decoder.params); err != nil {
+		return nil, err
+	}
+	return address.RawAddress(rawAddr), nil
+}
+
+// verifyRawAddress checks that the raw address has a P2PKH or P2SH prefix for
+// the given network, and a valid checksum.
+func verifyRawAddress(rawAddr []byte, params *ChainParams) error {
+	var prefix []byte
+	switch {
+	case bytes.HasPrefix(rawAddr, params.PubKeyHashAddrIDs):
+		prefix = params.PubKeyHashAddrIDs
+	case bytes.HasPrefix(rawAddr, params.ScriptHashAddrIDs):
+		prefix = params.ScriptHashAddrIDs
+	default:
+		return fmt.Errorf("address of different network")
+	}
+	if len(rawAddr) != len(prefix)+24 {
+		return fmt.Errorf("expected address length %v, got address length %v", len(prefix)+24, len(rawAddr))
+	}
+	var chsum [4]byte
+	copy(chsum[:], rawAddr[len(rawAddr)-4:])
+	if checksum(rawAddr[:len(rawAddr)-4]) != chsum {
+		return base58.ErrChecksum
+	}
+	return nil
+}
+
+// EncodeWIF encodes the private key in the Wallet Import Format, using the
+// private key prefix of the given network.
+func EncodeWIF(privKey *btcec.PrivateKey, compressPubKey bool, params *ChainParams) (string, error) {
+	wif, err := btcutil.NewWIF(privKey, params.Params, compressPubKey)
+	if err != nil {
+		return "", err
+	}
+	return wif.String(), nil
+}
+
+// DecodeWIF decodes a private key in the Wallet Import Format, and checks that
+// it uses the private key prefix of the given network.
+func DecodeWIF(wif string, params *ChainParams) (*btcutil.WIF, error) {
+	decoded, err := btcutil.DecodeWIF(wif)
+	if err != nil {
+		return nil, err
+	}
+	if !decoded.IsForNet(params.Params) {
+		return nil, fmt.Errorf("key of different network")
+	}
+	return decoded, nil
+}
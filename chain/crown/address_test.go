@@ -0,0 +1,70 @@
+package crown_test
+
+import (
+	"bytes"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/crown"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Address", func() {
+	hash := bytes.Repeat([]byte{0xab}, 20)
+
+	Context("when encoding and decoding a P2PKH address", func() {
+		It("should round-trip", func() {
+			addr, err := crown.NewAddressPubKeyHash(hash, &crown.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+
+			encodeDecoder := crown.NewAddressEncodeDecoder(&crown.MainNetParams)
+			rawAddr, err := encodeDecoder.DecodeAddress(address.Address(addr.EncodeAddress()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(rawAddr[:3])).To(Equal(crown.MainNetParams.PubKeyHashAddrIDs))
+			Expect([]byte(rawAddr[3:23])).To(Equal(hash))
+
+			encodedAddr, err := encodeDecoder.EncodeAddress(rawAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encodedAddr).To(Equal(address.Address(addr.EncodeAddress())))
+		})
+	})
+
+	Context("when encoding and decoding a P2SH address", func() {
+		It("should round-trip", func() {
+			addr, err := crown.NewAddressScriptHash(hash, &crown.TesnetParams)
+			Expect(err).ToNot(HaveOccurred())
+
+			encodeDecoder := crown.NewAddressEncodeDecoder(&crown.TesnetParams)
+			rawAddr, err := encodeDecoder.DecodeAddress(address.Address(addr.EncodeAddress()))
+			Expect(err).ToNot(HaveOccurred())
+			encodedAddr, err := encodeDecoder.EncodeAddress(rawAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encodedAddr).To(Equal(address.Address(addr.EncodeAddress())))
+		})
+	})
+
+	Context("when decoding an address for a different network", func() {
+		It("should return an error", func() {
+			addr, err := crown.NewAddressPubKeyHash(hash, &crown.TesnetParams)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = crown.NewAddressDecoder(&crown.MainNetParams).DecodeAddress(address.Address(addr.EncodeAddress()))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when decoding an address with a bad checksum", func() {
+		It("should return an error", func() {
+			addr, err := crown.NewAddressPubKeyHash(hash, &crown.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			encoded := []byte(addr.EncodeAddress())
+			if encoded[len(encoded)-1] == '1' {
+				encoded[len(encoded)-1] = '2'
+			} else {
+				encoded[len(encoded)-1] = '1'
+			}
+			_, err = crown.NewAddressDecoder(&crown.MainNetParams).DecodeAddress(address.Address(encoded))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
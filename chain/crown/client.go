@@ -0,0 +1,385 @@
+package crown
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+// The amount of CRW that must be locked in an output for it to be used as the
+// collateral of a node.
+const (
+	MasternodeCollateral = 10000 * btcutil.SatoshiPerBitcoin
+	SystemnodeCollateral = 500 * btcutil.SatoshiPerBitcoin
+)
+
+// A NodeType identifies the kind of Crown node.
+type NodeType string
+
+// Enumerate the kinds of Crown node.
+const (
+	Masternode = NodeType("masternode")
+	Systemnode = NodeType("systemnode")
+)
+
+// NodeInfo is an entry in the list of nodes known by the network.
+type NodeInfo struct {
+	// Outpoint of the collateral locked by the node.
+	Outpoint utxo.Outpoint
+	// Status of the node (for example, "ENABLED").
+	Status        string
+	Protocol      int64
+	Payee         address.Address
+	LastSeen      int64
+	ActiveSeconds int64
+	LastPaid      int64
+	// Addr is the IP address and port of the node.
+	Addr string
+}
+
+// NodeStatus is the status of the node run by the Crown node that is serving
+// the RPC requests.
+type NodeStatus struct {
+	Outpoint utxo.Outpoint
+	Service  string
+	Payee    address.Address
+	Status   string
+}
+
+// A PaymentWinner is the node that is scheduled to be paid at a block height.
+type PaymentWinner struct {
+	Height int64
+	Payee  address.Address
+	Votes  int64
+}
+
+// A Client interacts with an instance of the Crown network using the RPC
+// interface exposed by a Crown node. It extends the Bitcoin client with calls
+// that are specific to Crown: InstantSend, masternodes and systemnodes,
+// governance, and NFTs.
+type Client interface {
+	bitcoin.Client
+	bitcoin.FeeEstimatorClient
+	// RelayFee returns the minimum fee (in CRW/kB) that the node requires in
+	// order to relay transactions.
+	RelayFee(ctx context.Context) (float64, error)
+	// InstantLocked returns whether or not the transaction has been locked by
+	// InstantSend.
+	InstantLocked(ctx context.Context, txHash pack.Bytes) (bool, error)
+	// Final returns whether or not the transaction has been locked by
+	// InstantSend, or has at least the given number of confirmations.
+	Final(ctx context.Context, txHash pack.Bytes, minConf int64) (bool, error)
+	// SubmitTxInstant submits a signed transaction to the network, and
+	// requests that it is locked by InstantSend.
+	SubmitTxInstant(ctx context.Context, tx utxo.Tx) error
+	// NodeList returns all nodes of the given type that are known by the
+	// network.
+	NodeList(ctx context.Context, nodeType NodeType) ([]NodeInfo, error)
+	// NodeStatus returns the status of the node of the given type that is run
+	// by the Crown node.
+	NodeStatus(ctx context.Context, nodeType NodeType) (NodeStatus, error)
+	// NodeOutputs returns the outputs in the wallet of the Crown node that can
+	// be used as collateral for nodes of the given type.
+	NodeOutputs(ctx context.Context, nodeType NodeType) ([]utxo.Outpoint, error)
+	// NodeWinners returns the nodes of the given type that are scheduled to be
+	// paid in recent, and upcoming, blocks.
+	NodeWinners(ctx context.Context, nodeType NodeType, count int64) ([]PaymentWinner, error)
+	// CollateralOutpoints returns the outpoints that are locked as collateral
+	// for masternodes and systemnodes, including the outputs in the wallet of
+	// the Crown node that can be used as collateral.
+	CollateralOutpoints(ctx context.Context) ([]utxo.Outpoint, error)
+	// SpendableOutputs returns the unspent outputs of the given address that
+	// are not locked as collateral.
+	SpendableOutputs(ctx context.Context, minConf, maxConf int64, address address.Address) ([]utxo.Output, error)
+	// Proposals returns all budget proposals known by the network.
+	Proposals(ctx context.Context) ([]Proposal, error)
+	// BudgetProjection returns the budget proposals that will be paid in the
+	// next superblock.
+	BudgetProjection(ctx context.Context) ([]Proposal, error)
+	// ProposalVotes returns the votes cast for, or against, a budget proposal.
+	ProposalVotes(ctx context.Context, name string) ([]ProposalVote, error)
+	// FinalizedBudgets returns all finalized budgets known by the network.
+	FinalizedBudgets(ctx context.Context) ([]FinalizedBudget, error)
+	// NFTProtocols returns the NFT protocols that have been registered.
+	NFTProtocols(ctx context.Context, count, skip int64) ([]NFTProtocol, error)
+	// NFTProtocol returns the NFT protocol with the given identifier.
+	NFTProtocol(ctx context.Context, id string) (NFTProtocol, error)
+	// NFTs returns the tokens that have been registered, filtered by protocol
+	// and owner.
+	NFTs(ctx context.Context, protocolID string, owner address.Address, count, skip int64) ([]NFT, error)
+	// NFT returns the token with the given protocol and identifier.
+	NFT(ctx context.Context, protocolID string, id pack.Bytes32) (NFT, error)
+	// NFTOwner returns the owner of the token with the given protocol and
+	// identifier.
+	NFTOwner(ctx context.Context, protocolID string, id pack.Bytes32) (address.Address, error)
+	// RegisterNFTProtocolWithWallet registers an NFT protocol using the wallet
+	// of the Crown node, and returns the hash of the registration transaction.
+	// Use TxBuilder.BuildNFTProtocolRegistrationTx to register a protocol
+	// without the wallet.
+	RegisterNFTProtocolWithWallet(ctx context.Context, registration NFTProtocolRegistration) (pack.Bytes, error)
+	// IssueNFTWithWallet registers a token using the wallet of the Crown node,
+	// and returns the hash of the registration transaction. Use
+	// TxBuilder.BuildNFTIssuanceTx to register a token without the wallet.
+	IssueNFTWithWallet(ctx context.Context, issuance NFTIssuance) (pack.Bytes, error)
+}
+
+type client struct {
+	bitcoin.Client
+	bitcoin.FeeEstimatorClient
+	bitcoin.RPCCaller
+}
+
+// NewClient returns a new Client.
+func NewClient(opts ClientOptions) Client {
+	btcClient := bitcoin.NewClient(opts)
+	return &client{
+		Client:             btcClient,
+		FeeEstimatorClient: btcClient.(bitcoin.FeeEstimatorClient),
+		RPCCaller:          btcClient.(bitcoin.RPCCaller),
+	}
+}
+
+// RelayFee returns the minimum fee (in CRW/kB) that the node requires in order
+// to relay transactions.
+func (client *client) RelayFee(ctx context.Context) (float64, error) {
+	resp := struct {
+		RelayFee float64 `json:"relayfee"`
+	}{}
+	if err := client.Call(ctx, &resp, "getnetworkinfo"); err != nil {
+		return 0, fmt.Errorf("bad \"getnetworkinfo\": %v", err)
+	}
+	return resp.RelayFee, nil
+}
+
+// NodeList returns all nodes of the given type that are known by the network.
+func (client *client) NodeList(ctx context.Context, nodeType NodeType) ([]NodeInfo, error) {
+	method := string(nodeType) + "list"
+	resp := map[string]string{}
+	if err := client.Call(ctx, &resp, method, "full"); err != nil {
+		return nil, fmt.Errorf("bad \"%v\": %v", method, err)
+	}
+	nodes := make([]NodeInfo, 0, len(resp))
+	for key, value := range resp {
+		outpoint, err := decodeOutpoint(key)
+		if err != nil {
+			return nil, fmt.Errorf("bad outpoint: %v", err)
+		}
+		node, err := decodeNodeInfo(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad node %v: %v", key, err)
+		}
+		node.Outpoint = outpoint
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return bytes.Compare(nodes[i].Outpoint.Hash, nodes[j].Outpoint.Hash) < 0 ||
+			bytes.Equal(nodes[i].Outpoint.Hash, nodes[j].Outpoint.Hash) && nodes[i].Outpoint.Index.Uint32() < nodes[j].Outpoint.Index.Uint32()
+	})
+	return nodes, nil
+}
+
+// NodeStatus returns the status of the node of the given type that is run by
+// the Crown node.
+func (client *client) NodeStatus(ctx context.Context, nodeType NodeType) (NodeStatus, error) {
+	method := string(nodeType)
+	resp := struct {
+		Vin     string `json:"vin"`
+		Service string `json:"service"`
+		Payee   string `json:"payee"`
+		PubKey  string `json:"pubkey"`
+		Status  string `json:"status"`
+	}{}
+	if err := client.Call(ctx, &resp, method, "status"); err != nil {
+		return NodeStatus{}, fmt.Errorf("bad \"%v status\": %v", method, err)
+	}
+	outpoint, err := decodeOutpoint(resp.Vin)
+	if err != nil {
+		return NodeStatus{}, fmt.Errorf("bad outpoint: %v", err)
+	}
+	payee := resp.Payee
+	if payee == "" {
+		payee = resp.PubKey
+	}
+	return NodeStatus{
+		Outpoint: outpoint,
+		Service:  resp.Service,
+		Payee:    address.Address(payee),
+		Status:   resp.Status,
+	}, nil
+}
+
+// NodeOutputs returns the outputs in the wallet of the Crown node that can be
+// used as collateral for nodes of the given type.
+func (client *client) NodeOutputs(ctx context.Context, nodeType NodeType) ([]utxo.Outpoint, error) {
+	method := string(nodeType)
+	resp := map[string]json.Number{}
+	if err := client.Call(ctx, &resp, method, "outputs"); err != nil {
+		return nil, fmt.Errorf("bad \"%v outputs\": %v", method, err)
+	}
+	outpoints := make([]utxo.Outpoint, 0, len(resp))
+	for txid, index := range resp {
+		outpoint, err := decodeOutpoint(fmt.Sprintf("%v-%v", txid, index))
+		if err != nil {
+			return nil, fmt.Errorf("bad outpoint: %v", err)
+		}
+		outpoints = append(outpoints, outpoint)
+	}
+	return outpoints, nil
+}
+
+// NodeWinners returns the nodes of the given type that are scheduled to be
+// paid in recent, and upcoming, blocks. Blocks for which the winner is not yet
+// known are omitted.
+func (client *client) NodeWinners(ctx context.Context, nodeType NodeType, count int64) ([]PaymentWinner, error) {
+	method := string(nodeType)
+	resp := map[string]string{}
+	if err := client.Call(ctx, &resp, method, "winners", strconv.FormatInt(count, 10)); err != nil {
+		return nil, fmt.Errorf("bad \"%v winners\": %v", method, err)
+	}
+	winners := make([]PaymentWinner, 0, len(resp))
+	for key, value := range resp {
+		height, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad height: %v", err)
+		}
+		// Each value is a comma separated list of "payee:votes" pairs, the
+		// first of which has the most votes.
+		payeeAndVotes := strings.Split(strings.TrimSpace(strings.Split(value, ",")[0]), ":")
+		if len(payeeAndVotes) != 2 {
+			// The winner is not yet known.
+			continue
+		}
+		votes, err := strconv.ParseInt(payeeAndVotes[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad votes: %v", err)
+		}
+		winners = append(winners, PaymentWinner{
+			Height: height,
+			Payee:  address.Address(payeeAndVotes[0]),
+			Votes:  votes,
+		})
+	}
+	sort.Slice(winners, func(i, j int) bool {
+		return winners[i].Height < winners[j].Height
+	})
+	return winners, nil
+}
+
+// CollateralOutpoints returns the outpoints that are locked as collateral for
+// masternodes and systemnodes, including the outputs in the wallet of the
+// Crown node that can be used as collateral.
+func (client *client) CollateralOutpoints(ctx context.Context) ([]utxo.Outpoint, error) {
+	outpoints := []utxo.Outpoint{}
+	for _, nodeType := range []NodeType{Masternode, Systemnode} {
+		nodes, err := client.NodeList(ctx, nodeType)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			outpoints = append(outpoints, node.Outpoint)
+		}
+		nodeOutputs, err := client.NodeOutputs(ctx, nodeType)
+		if err != nil {
+			return nil, err
+		}
+		outpoints = append(outpoints, nodeOutputs...)
+	}
+	return outpoints, nil
+}
+
+// SpendableOutputs returns the unspent outputs of the given address that are
+// not locked as collateral.
+func (client *client) SpendableOutputs(ctx context.Context, minConf, maxConf int64, addr address.Address) ([]utxo.Output, error) {
+	outputs, err := client.UnspentOutputs(ctx, minConf, maxConf, addr)
+	if err != nil {
+		return nil, err
+	}
+	collateral, err := client.CollateralOutpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ExcludeCollateral(outputs, collateral), nil
+}
+
+// ExcludeCollateral returns the outputs that are not locked as collateral.
+func ExcludeCollateral(outputs []utxo.Output, collateral []utxo.Outpoint) []utxo.Output {
+	locked := make(map[string]bool, len(collateral))
+	for _, outpoint := range collateral {
+		locked[outpointKey(outpoint)] = true
+	}
+	spendable := make([]utxo.Output, 0, len(outputs))
+	for _, output := range outputs {
+		if locked[outpointKey(output.Outpoint)] {
+			continue
+		}
+		spendable = append(spendable, output)
+	}
+	return spendable
+}
+
+func outpointKey(outpoint utxo.Outpoint) string {
+	return fmt.Sprintf("%x-%v", []byte(outpoint.Hash), outpoint.Index.Uint32())
+}
+
+// outpointPattern matches outpoints in the "txid-index" format, and the
+// "COutPoint(txid, index)" format used by older versions of Crown Core.
+var outpointPattern = regexp.MustCompile(`([0-9a-fA-F]{64})(?:-|, )(\d+)`)
+
+// decodeOutpoint from a string, as returned by the RPC interface.
+func decodeOutpoint(str string) (utxo.Outpoint, error) {
+	match := outpointPattern.FindStringSubmatch(str)
+	if match == nil {
+		return utxo.Outpoint{}, fmt.Errorf("unexpected format %q", str)
+	}
+	txid, err := chainhash.NewHashFromStr(match[1])
+	if err != nil {
+		return utxo.Outpoint{}, err
+	}
+	index, err := strconv.ParseUint(match[2], 10, 32)
+	if err != nil {
+		return utxo.Outpoint{}, err
+	}
+	return utxo.Outpoint{
+		Hash:  pack.NewBytes(txid[:]),
+		Index: pack.NewU32(uint32(index)),
+	}, nil
+}
+
+// decodeNodeInfo from the "full" format of the node list, which is a space
+// separated list of the status, protocol, payee, last seen time, active
+// seconds, last paid time, and address.
+func decodeNodeInfo(str string) (NodeInfo, error) {
+	fields := strings.Fields(str)
+	if len(fields) < 7 {
+		return NodeInfo{}, fmt.Errorf("expected at least 7 fields, got %v", len(fields))
+	}
+	ints := make([]int64, 4)
+	for i, field := range []string{fields[1], fields[3], fields[4], fields[5]} {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return NodeInfo{}, fmt.Errorf("bad field %q: %v", field, err)
+		}
+		ints[i] = n
+	}
+	return NodeInfo{
+		Status:        fields[0],
+		Protocol:      ints[0],
+		Payee:         address.Address(fields[2]),
+		LastSeen:      ints[1],
+		ActiveSeconds: ints[2],
+		LastPaid:      ints[3],
+		Addr:          fields[len(fields)-1],
+	}, nil
+}
@@ -0,0 +1,184 @@
+package crown_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newStandIn returns a server that responds to JSON-RPC requests using the
+// given results, keyed by the method and the first param (or by the method
+// alone). Results that are *btcjson.RPCErrors are returned as errors. Methods
+// without a result are not found.
+func newStandIn(results map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}{}
+		Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+		result, ok := results[req.Method]
+		if len(req.Params) > 0 {
+			if resultForParam, okForParam := results[fmt.Sprintf("%v %v", req.Method, req.Params[0])]; okForParam {
+				result, ok = resultForParam, okForParam
+			}
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":1}`)
+			return
+		}
+		if rpcErr, ok := result.(*btcjson.RPCError); ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			Expect(json.NewEncoder(w).Encode(map[string]interface{}{"result": nil, "error": rpcErr, "id": 1})).To(Succeed())
+			return
+		}
+		Expect(json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil, "id": 1})).To(Succeed())
+	}))
+}
+
+var _ = Describe("Client", func() {
+	txid1 := strings.Repeat("11", 32)
+	txid2 := strings.Repeat("22", 32)
+	txid3 := strings.Repeat("33", 32)
+	newOutpoint := func(txid string, index uint32) utxo.Outpoint {
+		hash, err := chainhash.NewHashFromStr(txid)
+		Expect(err).ToNot(HaveOccurred())
+		return utxo.Outpoint{Hash: pack.NewBytes(hash[:]), Index: pack.NewU32(index)}
+	}
+
+	results := map[string]interface{}{
+		"masternodelist full": map[string]string{
+			txid1 + "-1": "  ENABLED 70057 CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6 1600000000 86400 1599990000 192.168.0.1:9340",
+		},
+		"systemnodelist full": map[string]string{
+			"COutPoint(" + txid2 + ", 0)": "  ENABLED 70057 CRWRyvXjvQmWDMgxb7YESSVJBVd4gHJQbEK9 1600000000 3600 0 192.168.0.2:9340",
+		},
+		"masternode outputs": map[string]string{txid3: "2"},
+		"systemnode outputs": map[string]string{},
+		"masternode status": map[string]string{
+			"vin":     "CTxIn(COutPoint(" + txid1 + ", 1), scriptSig=)",
+			"service": "192.168.0.1:9340",
+			"payee":   "CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6",
+			"status":  "Masternode successfully started",
+		},
+		"systemnode status": btcjson.NewRPCError(btcjson.ErrRPCMisc, "This is not a systemnode"),
+		"masternode winners": map[string]string{
+			"101": "CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6:10",
+			"100": "CRWRyvXjvQmWDMgxb7YESSVJBVd4gHJQbEK9:8, CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6:2",
+			"102": "Unknown",
+		},
+	}
+
+	Context("when listing nodes", func() {
+		It("should decode both outpoint formats", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			masternodes, err := client.NodeList(context.Background(), crown.Masternode)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(masternodes).To(Equal([]crown.NodeInfo{{
+				Outpoint:      newOutpoint(txid1, 1),
+				Status:        "ENABLED",
+				Protocol:      70057,
+				Payee:         address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"),
+				LastSeen:      1600000000,
+				ActiveSeconds: 86400,
+				LastPaid:      1599990000,
+				Addr:          "192.168.0.1:9340",
+			}}))
+
+			systemnodes, err := client.NodeList(context.Background(), crown.Systemnode)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(systemnodes).To(HaveLen(1))
+			Expect(systemnodes[0].Outpoint).To(Equal(newOutpoint(txid2, 0)))
+		})
+	})
+
+	Context("when getting the node status", func() {
+		It("should decode the collateral outpoint", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			status, err := client.NodeStatus(context.Background(), crown.Masternode)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(crown.NodeStatus{
+				Outpoint: newOutpoint(txid1, 1),
+				Service:  "192.168.0.1:9340",
+				Payee:    address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"),
+				Status:   "Masternode successfully started",
+			}))
+		})
+	})
+
+	Context("when the node returns an error", func() {
+		It("should return the error without retrying", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			// The context is never done, so this would block forever if the
+			// error was retried.
+			_, err := client.NodeStatus(context.Background(), crown.Systemnode)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("This is not a systemnode"))
+		})
+	})
+
+	Context("when getting the payment winners", func() {
+		It("should return the known winners in order of height", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			winners, err := client.NodeWinners(context.Background(), crown.Masternode, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(winners).To(Equal([]crown.PaymentWinner{
+				{Height: 100, Payee: address.Address("CRWRyvXjvQmWDMgxb7YESSVJBVd4gHJQbEK9"), Votes: 8},
+				{Height: 101, Payee: address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"), Votes: 10},
+			}))
+		})
+	})
+
+	Context("when excluding collateral", func() {
+		It("should only return outputs that are not locked", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			collateral, err := client.CollateralOutpoints(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(collateral).To(ConsistOf(newOutpoint(txid1, 1), newOutpoint(txid2, 0), newOutpoint(txid3, 2)))
+
+			newOutput := func(outpoint utxo.Outpoint) utxo.Output {
+				return utxo.Output{
+					Outpoint:     outpoint,
+					Value:        pack.NewU256FromU64(pack.NewU64(crown.MasternodeCollateral)),
+					PubKeyScript: pack.Bytes(bytes.Repeat([]byte{0x00}, 25)),
+				}
+			}
+			outputs := []utxo.Output{
+				newOutput(newOutpoint(txid1, 0)),
+				newOutput(newOutpoint(txid1, 1)),
+				newOutput(newOutpoint(txid3, 2)),
+			}
+			Expect(crown.ExcludeCollateral(outputs, collateral)).To(Equal(outputs[:1]))
+		})
+	})
+})
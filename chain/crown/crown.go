@@ -1,6 +1,7 @@
 package crown
 
 import (
+	"fmt"
 	"time"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -66,24 +67,63 @@ var (
 			{Height: 1100000, Hash: strToHash("f172bdb7a894b9e055eae4b1b2e8d91aba9404d24fa808985346cc7c8eea35a6")},
 		},
 		PrivateKeyID:     128,
+		HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4}, // xprv
+		HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e}, // xpub
 		CoinbaseMaturity: 100,
 	}
 	TestParams = chaincfg.Params{
 		Name:                     "testnet",
+		Net:                      0x060e180f,
 		DefaultPort:              "19340",
 		GenesisBlock:             &genBlock,
 		GenesisHash:              &genBlockHash,
 		TargetTimespan:           time.Hour * 24 * 14,
 		TargetTimePerBlock:       time.Second * 90,
 		SubsidyReductionInterval: 130000,
-		PrivateKeyID:             239,
+		Checkpoints: []chaincfg.Checkpoint{
+			{Height: 0, Hash: &genBlockHash},
+		},
+		PrivateKeyID:     239,
+		HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+		HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+		CoinbaseMaturity: 100,
+	}
+
+	// The regression test network uses the same coinbase as the main network,
+	// but with the minimum difficulty so that blocks can be mined instantly.
+	regGenBlockHash = chainhash.Hash([chainhash.HashSize]byte{
+		0x6f, 0x91, 0x0e, 0x09, 0x4c, 0x3b, 0xa1, 0x32, 0x96,
+		0xa2, 0xe9, 0x75, 0xf2, 0x73, 0xef, 0xdf, 0xde, 0x0f,
+		0xc5, 0x0a, 0x7e, 0x4b, 0x63, 0x6b, 0x32, 0xce, 0xb2,
+		0x41, 0x16, 0x49, 0xc6, 0xdc,
+	})
+	regGenBlock = wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			PrevBlock:  chainhash.Hash{},         // 0000000000000000000000000000000000000000000000000000000000000000
+			MerkleRoot: genMerkleRoot,            // 80ad356118a9ab8db192db66ef77146cc36d958f959251feace550e4ca3d1446
+			Timestamp:  time.Unix(1296688602, 0), // 2nd Feb 2011 23:16:42
+			Bits:       0x207fffff,
+			Nonce:      2,
+		},
 	}
+
 	RegParams = chaincfg.Params{
 		Name:                     "regtest",
+		Net:                      0xdab5bffa,
 		DefaultPort:              "19445",
+		GenesisBlock:             &regGenBlock,
+		GenesisHash:              &regGenBlockHash,
 		TargetTimespan:           time.Hour * 48,
 		TargetTimePerBlock:       time.Second * 60,
 		SubsidyReductionInterval: 150,
+		Checkpoints: []chaincfg.Checkpoint{
+			{Height: 0, Hash: &regGenBlockHash},
+		},
+		PrivateKeyID:     239,
+		HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+		HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+		CoinbaseMaturity: 100,
 	}
 )
 var MainNetParams = ChainParams{
@@ -96,10 +136,27 @@ var TesnetParams = ChainParams{
 	PubKeyHashAddrIDs: []byte{0x01, 0x7A, 0xCD, 0x67},
 	ScriptHashAddrIDs: []byte{0x01, 0x7A, 0xCD, 0x51},
 }
+
+// RegressionNetParams uses the same address prefixes as the test network.
 var RegressionNetParams = ChainParams{
 	Params:            &RegParams,
-	PubKeyHashAddrIDs: []byte{0x01, 0x75, 0x07},
-	ScriptHashAddrIDs: []byte{0x01, 0x74, 0xF1},
+	PubKeyHashAddrIDs: []byte{0x01, 0x7A, 0xCD, 0x67},
+	ScriptHashAddrIDs: []byte{0x01, 0x7A, 0xCD, 0x51},
+}
+
+// ParamsForNetwork returns the chain parameters of the Crown network with the
+// given name ("mainnet", "testnet", or "regtest").
+func ParamsForNetwork(network string) (*ChainParams, error) {
+	switch network {
+	case MainParams.Name:
+		return &MainNetParams, nil
+	case TestParams.Name:
+		return &TesnetParams, nil
+	case RegParams.Name:
+		return &RegressionNetParams, nil
+	default:
+		return nil, fmt.Errorf("unknown network %v", network)
+	}
 }
 
 func strToHash(Str string) *chainhash.Hash {
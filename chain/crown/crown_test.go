@@ -16,10 +16,6 @@ import (
         ."github.com/onsi/gomega"
 )
 
-/* const (
-        fee := pack.NewU64(1000) 
-) */
-
 var _ = Describe("Crown", func() {
         Context("when submitting transactions", func() {
                 Context("when sending CRW to multiple addresses", func() {
@@ -27,22 +23,29 @@ var _ = Describe("Crown", func() {
                                 // Load private key, and assume that the associated address has
                                 // funds to spend. You can do this by setting CROWN_PK to the
                                 // value specified in the `./multichaindeploy/.env` file.
-                                fee := pack.NewU64(1000) 
                                 pkEnv := os.Getenv("CROWN_PK")
                                 if pkEnv == "" {
                                         panic("CROWN_PK is undefined")
                                 }
-                                wif, err := btcutil.DecodeWIF(pkEnv)
+                                // The network of the node defaults to mainnet, and can be
+                                // set using CROWN_NETWORK.
+                                network := os.Getenv("CROWN_NETWORK")
+                                if network == "" {
+                                        network = "mainnet"
+                                }
+                                params, err := crown.ParamsForNetwork(network)
+                                Expect(err).ToNot(HaveOccurred())
+                                wif, err := crown.DecodeWIF(pkEnv, params)
                                 Expect(err).ToNot(HaveOccurred())
 								
                                 // PKH
                                 pkhAddr, err := crown.NewAddressPubKeyHash(btcutil.
                                         Hash160(wif.PrivKey.PubKey().SerializeCompressed()),
-                                        &crown.MainNetParams)
+                                        params)
                                 Expect(err).ToNot(HaveOccurred())
                                 pkhAddrUncompressed, err := crown.NewAddressPubKeyHash(btcutil.
                                         Hash160(wif.PrivKey.PubKey().SerializeUncompressed()),
-                                        &crown.MainNetParams)
+                                        params)
                                 Expect(err).ToNot(HaveOccurred())
                                 log.Printf("PKH                %v", pkhAddr.EncodeAddress())
                                 log.Printf("PKH (uncompressed) %v", pkhAddrUncompressed.EncodeAddress())
@@ -81,17 +84,29 @@ var _ = Describe("Crown", func() {
                                                 Value:        output.Value,
                                         }},
                                 }
+                                // Split the output between two recipients, and then
+                                // deduct the fee for the size of the transaction from
+                                // the first recipient.
+                                half := pack.NewU256FromU64(pack.NewU64(output.Value.Int().Uint64() / 2))
                                 recipients := []utxo.Recipient{
                                         {
                                                 To:    address.Address(pkhAddr.EncodeAddress()),
-                                                Value: pack.NewU256FromU64(pack.NewU64((output.Value.Int().Uint64() - 1000) / 2) - fee ),
+                                                Value: half,
                                         },
                                         {
                                                 To:    address.Address(pkhAddrUncompressed.EncodeAddress()),
-                                                Value: pack.NewU256FromU64(pack.NewU64((output.Value.Int().Uint64() - 1000) / 2)),
-										},
-									}
-                                tx, err := crown.NewTxBuilder(&crown.MainNetParams).BuildTx(inputs, recipients)
+                                                Value: half,
+                                        },
+                                }
+                                txBuilder := crown.NewTxBuilder(params)
+                                tx, err := txBuilder.BuildTx(inputs, recipients)
+                                Expect(err).ToNot(HaveOccurred())
+                                satsPerByte, err := crown.NewNodeGasEstimator(client, 2, pack.NewU256FromU64(pack.NewU64(crown.DefaultMaxSatsPerByte))).EstimateGasPrice(context.Background())
+                                Expect(err).ToNot(HaveOccurred())
+                                fee, err := crown.Fee(tx.(*crown.Tx), satsPerByte)
+                                Expect(err).ToNot(HaveOccurred())
+                                recipients[0].Value = half.Sub(fee)
+                                tx, err = txBuilder.BuildTx(inputs, recipients)
                                 Expect(err).ToNot(HaveOccurred())
                                 // Get the digests that need signing from the transaction, and
                                 // sign them. In production, this would be done using the RZL
@@ -1,19 +1,126 @@
 package crown
 
 import (
-	"context" 
-	"math/big"  
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
 	"github.com/renproject/pack"
 )
 
-// Fixed fee amount since Crown doesn't allows too high fees
+// DefaultMaxSatsPerByte is the default maximum SATs-per-byte returned by the
+// NodeGasEstimator. At this rate, the fee of a standard transaction (at most
+// 100kB) is at most 0.1 CRW.
+const DefaultMaxSatsPerByte = 100
+
+// GasEstimator returns a fixed number of SATs-per-byte.
+type GasEstimator = bitcoin.GasEstimator
 
-var Gas = big.NewInt(10000)
+// NewGasEstimator returns a simple gas estimator that always returns the given
+// number of SATs-per-byte.
+var NewGasEstimator = bitcoin.NewGasEstimator
 
-type Estimator struct {} 
+// A NodeGasEstimator returns the SATs-per-byte that is needed in order to
+// confirm transactions within a target number of blocks, as estimated by a
+// Crown node. The estimate is never less than the minimum relay fee of the
+// node, and never more than the given maximum, so that transactions are
+// neither rejected for paying too little nor for paying too much.
+type NodeGasEstimator struct {
+	client         Client
+	numBlocks      int64
+	maxSatsPerByte pack.U256
+}
+
+// NewNodeGasEstimator returns a gas estimator that uses the fee estimates of
+// the node to target confirmation within the given number of blocks, bounded
+// by the minimum relay fee of the node and the given maximum SATs-per-byte.
+func NewNodeGasEstimator(client Client, numBlocks int64, maxSatsPerByte pack.U256) NodeGasEstimator {
+	return NodeGasEstimator{
+		client:         client,
+		numBlocks:      numBlocks,
+		maxSatsPerByte: maxSatsPerByte,
+	}
+}
 
-// Implementation to get the real Gas from fees
+// EstimateGasPrice returns the number of SATs-per-byte that is needed in order
+// to confirm transactions within the target number of blocks. Use Fee to get
+// the absolute fee of a transaction at this rate.
+func (gasEstimator NodeGasEstimator) EstimateGasPrice(ctx context.Context) (pack.U256, error) {
+	crwPerKB, err := gasEstimator.client.RelayFee(ctx)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	// Convert from CRW/kB to SATs-per-byte, rounding up.
+	satsPerKB := uint64(math.Round(crwPerKB * btcutil.SatoshiPerBitcoin))
+	floor := pack.NewU256FromU64(pack.NewU64((satsPerKB + 999) / 1000))
+	if floor.Int().Cmp(gasEstimator.maxSatsPerByte.Int()) > 0 {
+		return pack.U256{}, fmt.Errorf("bad max SATs-per-byte: %v is less than the min relay fee %v", gasEstimator.maxSatsPerByte, floor)
+	}
+
+	satsPerByte, err := bitcoin.NewNodeGasEstimator(gasEstimator.client, gasEstimator.numBlocks, floor).EstimateGasPrice(ctx)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	if satsPerByte.Int().Cmp(gasEstimator.maxSatsPerByte.Int()) > 0 {
+		return gasEstimator.maxSatsPerByte, nil
+	}
+	return satsPerByte, nil
+}
+
+// Fee returns the absolute fee (in SATs) that the transaction must pay, once it
+// has been signed, at the given SATs-per-byte. P2PKH inputs are assumed to be
+// signed with a compressed public key. P2SH inputs that spend a standard
+// multisig are sized from their redeem script (which must be the sig script of
+// the input), and other P2SH inputs are assumed to need one signature and a
+// compressed public key. Special transactions also pay for their payload and
+// its signature. Use FeeWithInputSizes when inputs are signed differently.
+func Fee(tx *Tx, satsPerByte pack.U256) (pack.U256, error) {
+	return FeeWithInputSizes(tx, satsPerByte, nil)
+}
 
-func (Estimator) EstimateGasPrice(ctx context.Context) (pack.U256, error) { 
-return pack.NewU256FromInt(Gas), nil
+// FeeWithInputSizes is the same as Fee, but uses the given sizes (in bytes) of
+// the signed inputs instead of estimating them. A size of zero (or a missing
+// size) means that the size of the input is estimated. For example, a P2PKH
+// input that is signed with an uncompressed public key is 181 bytes.
+func FeeWithInputSizes(tx *Tx, satsPerByte pack.U256, inputSizes []int) (pack.U256, error) {
+	if len(inputSizes) > len(tx.inputs) {
+		return pack.U256{}, fmt.Errorf("expected at most %v input sizes, got %v", len(tx.inputs), len(inputSizes))
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad outputs: %v", err)
+	}
+	outputScripts := make([]pack.Bytes, len(outputs))
+	for i := range outputs {
+		outputScripts[i] = outputs[i].PubKeyScript
+	}
+	// Crown does not support segregated witness, so the weight of the
+	// transaction is always four times its size.
+	weight, err := utxo.EstimateWeight(tx.inputs, outputScripts)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad tx: %v", err)
+	}
+	size := (weight + 3) / 4
+	for i, inputSize := range inputSizes {
+		if inputSize <= 0 {
+			continue
+		}
+		inputWeight, err := utxo.InputWeight(tx.inputs[i])
+		if err != nil {
+			return pack.U256{}, fmt.Errorf("bad input %v: %v", i, err)
+		}
+		size += inputSize - inputWeight/4
+	}
+	if tx.txType != 0 {
+		// The payload, its compact signature, and their length prefixes.
+		payloadSize := len(tx.payload) + 1 + 65
+		size += wire.VarIntSerializeSize(uint64(payloadSize)) + payloadSize
+	}
+	fee := big.NewInt(int64(size))
+	return pack.NewU256FromInt(fee.Mul(fee, satsPerByte.Int())), nil
 }
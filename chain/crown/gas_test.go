@@ -1 +1,173 @@
 package crown_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gas", func() {
+	max := pack.NewU256FromU64(pack.NewU64(20))
+	relayFee := map[string]interface{}{"relayfee": 0.00002}
+
+	DescribeTable("when estimating the gas price using the node",
+		func(estimate interface{}, expected uint64) {
+			results := map[string]interface{}{"getnetworkinfo": relayFee}
+			if estimate != nil {
+				results["estimatesmartfee"] = estimate
+			}
+			server := newStandIn(results)
+			defer server.Close()
+
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+			satsPerByte, err := crown.NewNodeGasEstimator(client, 2, max).EstimateGasPrice(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(satsPerByte).To(Equal(pack.NewU256FromU64(pack.NewU64(expected))))
+		},
+
+		Entry("should return the estimate of the node", map[string]interface{}{"feerate": 0.00005, "blocks": 2}, uint64(5)),
+		Entry("should return the relay fee when the node has no estimate", map[string]interface{}{"errors": []string{"Insufficient data or no feerate found"}, "blocks": 0}, uint64(2)),
+		Entry("should return the relay fee when the estimate is below it", map[string]interface{}{"feerate": 0.00001, "blocks": 2}, uint64(2)),
+		Entry("should return the maximum when the estimate is above it", map[string]interface{}{"feerate": 0.001, "blocks": 2}, uint64(20)),
+	)
+
+	Context("when the maximum is less than the relay fee", func() {
+		It("should return an error", func() {
+			server := newStandIn(map[string]interface{}{"getnetworkinfo": relayFee})
+			defer server.Close()
+
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+			_, err := crown.NewNodeGasEstimator(client, 2, pack.NewU256FromU64(pack.NewU64(1))).EstimateGasPrice(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when computing the fee of a transaction", func() {
+		It("should pay for the size of the signed transaction", func() {
+			params := &crown.RegressionNetParams
+			addr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), params)
+			Expect(err).ToNot(HaveOccurred())
+			script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+
+			newInput := func(b byte) utxo.Input {
+				return utxo.Input{Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{b}, 32)), Index: pack.NewU32(0)},
+					PubKeyScript: pack.Bytes(script),
+					Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+				}}
+			}
+			recipient := utxo.Recipient{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(50000))}
+			txBuilder := crown.NewTxBuilder(params)
+			satsPerByte := pack.NewU256FromU64(pack.NewU64(2))
+
+			// 10 bytes of overhead, 149 bytes per input (assuming the maximum
+			// signature length), and 34 bytes per output.
+			tx, err := txBuilder.BuildTx([]utxo.Input{newInput(0x01)}, []utxo.Recipient{recipient, recipient})
+			Expect(err).ToNot(HaveOccurred())
+			fee, err := crown.Fee(tx.(*crown.Tx), satsPerByte)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(2 * 227))))
+
+			// The fee grows with the number of inputs.
+			tx, err = txBuilder.BuildTx([]utxo.Input{newInput(0x01), newInput(0x02), newInput(0x03)}, []utxo.Recipient{recipient})
+			Expect(err).ToNot(HaveOccurred())
+			fee, err = crown.Fee(tx.(*crown.Tx), satsPerByte)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(2 * (10 + 3*149 + 34)))))
+		})
+
+		It("should size inputs that are not signed with a compressed key", func() {
+			params := &crown.RegressionNetParams
+			pubKeys := make([]pack.Bytes, 3)
+			for i := range pubKeys {
+				privKey, err := btcec.NewPrivateKey(btcec.S256())
+				Expect(err).ToNot(HaveOccurred())
+				pubKeys[i] = pack.NewBytes(privKey.PubKey().SerializeCompressed())
+			}
+			redeemScript, err := crown.NewMultisigRedeemScript(2, pubKeys, params)
+			Expect(err).ToNot(HaveOccurred())
+			multisigAddr, err := crown.NewAddressMultisig(2, pubKeys, params)
+			Expect(err).ToNot(HaveOccurred())
+			multisigScript, err := txscript.PayToAddrScript(multisigAddr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			addr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), params)
+			Expect(err).ToNot(HaveOccurred())
+			script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			recipient := utxo.Recipient{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(50000))}
+			txBuilder := crown.NewTxBuilder(params)
+			satsPerByte := pack.NewU256FromU64(pack.NewU64(2))
+
+			// A 2-of-3 multisig input is 299 bytes: the outpoint and sequence,
+			// OP_0, two signatures, and the 105 byte redeem script.
+			tx, err := txBuilder.BuildTx([]utxo.Input{{
+				Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+					PubKeyScript: pack.Bytes(multisigScript),
+					Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+				},
+				SigScript: redeemScript,
+			}}, []utxo.Recipient{recipient})
+			Expect(err).ToNot(HaveOccurred())
+			fee, err := crown.Fee(tx.(*crown.Tx), satsPerByte)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(2 * (10 + 299 + 34)))))
+
+			// An uncompressed public key is 32 bytes larger.
+			tx, err = txBuilder.BuildTx([]utxo.Input{{
+				Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+					PubKeyScript: pack.Bytes(script),
+					Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+				},
+			}}, []utxo.Recipient{recipient})
+			Expect(err).ToNot(HaveOccurred())
+			fee, err = crown.FeeWithInputSizes(tx.(*crown.Tx), satsPerByte, []int{181})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(2 * (10 + 181 + 34)))))
+			_, err = crown.FeeWithInputSizes(tx.(*crown.Tx), satsPerByte, []int{181, 181})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should pay for the payload of special transactions", func() {
+			params := &crown.TesnetParams
+			addr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), params)
+			Expect(err).ToNot(HaveOccurred())
+			script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			inputs := []utxo.Input{{Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(script),
+				Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+			}}}
+			recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(50000))}}
+			txBuilder := crown.NewTxBuilder(params)
+			satsPerByte := pack.NewU256FromU64(pack.NewU64(2))
+
+			tx, err := txBuilder.BuildNFTProtocolRegistrationTx(inputs, recipients, crown.NFTProtocolRegistration{
+				ID:               "doc",
+				Name:             "Documents",
+				Owner:            address.Address(addr.EncodeAddress()),
+				RegSign:          crown.NFTRegSignSelf,
+				MetadataMimeType: "application/json",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			fee, err := crown.Fee(tx, satsPerByte)
+			Expect(err).ToNot(HaveOccurred())
+			// The 62 byte payload, its 65 byte signature, and their length
+			// prefixes.
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(2 * (10 + 149 + 34 + 1 + 62 + 1 + 65)))))
+		})
+	})
+})
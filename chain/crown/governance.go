@@ -0,0 +1,391 @@
+package crown
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// ProposalFee is the amount of CRW that must be burned by the collateral
+// transaction of a budget proposal.
+const ProposalFee = 10 * btcutil.SatoshiPerBitcoin
+
+// The number of blocks between superblocks, at which budget proposals are
+// paid.
+const (
+	MainNetBudgetCycleBlocks = 43200
+	TestNetBudgetCycleBlocks = 50
+)
+
+// A Proposal is a budget proposal that has been submitted to the network.
+type Proposal struct {
+	Name                  string
+	URL                   string
+	Hash                  pack.Bytes32
+	FeeHash               pack.Bytes32
+	BlockStart            int64
+	BlockEnd              int64
+	TotalPaymentCount     int64
+	RemainingPaymentCount int64
+	PaymentAddress        address.Address
+	Yeas                  int64
+	Nays                  int64
+	Abstains              int64
+	TotalPayment          pack.U256
+	MonthlyPayment        pack.U256
+	IsEstablished         bool
+	IsValid               bool
+	IsValidReason         string
+	// Allotted is the amount that will be paid to the proposal in the next
+	// superblock. It is only set by the budget projection.
+	Allotted pack.U256
+}
+
+// PaymentHeights returns the heights of the superblocks at which the proposal
+// will be paid, given the number of blocks between superblocks.
+func (proposal Proposal) PaymentHeights(cycleBlocks int64) []int64 {
+	heights := []int64{}
+	if cycleBlocks <= 0 {
+		return heights
+	}
+	start := proposal.BlockStart
+	if start%cycleBlocks != 0 {
+		start += cycleBlocks - start%cycleBlocks
+	}
+	for height := start; height <= proposal.BlockEnd && int64(len(heights)) < proposal.TotalPaymentCount; height += cycleBlocks {
+		heights = append(heights, height)
+	}
+	return heights
+}
+
+// A ProposalVote is a vote cast by a masternode for, or against, a budget
+// proposal.
+type ProposalVote struct {
+	// Outpoint of the collateral locked by the masternode that cast the vote.
+	Outpoint utxo.Outpoint
+	Hash     pack.Bytes32
+	Vote     string
+	Time     int64
+	IsValid  bool
+}
+
+// A FinalizedBudget is the set of proposals that will be paid in a
+// superblock.
+type FinalizedBudget struct {
+	Name       string
+	FeeTx      pack.Bytes32
+	Hash       pack.Bytes32
+	BlockStart int64
+	BlockEnd   int64
+	Proposals  []string
+	VoteCount  int64
+	Status     string
+}
+
+type proposalResult struct {
+	Name                  string  `json:"Name"`
+	URL                   string  `json:"URL"`
+	Hash                  string  `json:"Hash"`
+	FeeHash               string  `json:"FeeHash"`
+	BlockStart            int64   `json:"BlockStart"`
+	BlockEnd              int64   `json:"BlockEnd"`
+	TotalPaymentCount     int64   `json:"TotalPaymentCount"`
+	RemainingPaymentCount int64   `json:"RemainingPaymentCount"`
+	PaymentAddress        string  `json:"PaymentAddress"`
+	Yeas                  int64   `json:"Yeas"`
+	Nays                  int64   `json:"Nays"`
+	Abstains              int64   `json:"Abstains"`
+	TotalPayment          float64 `json:"TotalPayment"`
+	MonthlyPayment        float64 `json:"MonthlyPayment"`
+	IsEstablished         bool    `json:"IsEstablished"`
+	IsValid               bool    `json:"IsValid"`
+	IsValidReason         string  `json:"IsValidReason"`
+	Alloted               float64 `json:"Alloted"`
+}
+
+// Proposals returns all budget proposals known by the network, sorted by
+// name.
+func (client *client) Proposals(ctx context.Context) ([]Proposal, error) {
+	resp := map[string]proposalResult{}
+	if err := client.Call(ctx, &resp, "mnbudget", "show"); err != nil {
+		return nil, fmt.Errorf("bad \"mnbudget show\": %v", err)
+	}
+	results := make([]proposalResult, 0, len(resp))
+	for _, result := range resp {
+		results = append(results, result)
+	}
+	return decodeProposals(results)
+}
+
+// BudgetProjection returns the budget proposals that will be paid in the next
+// superblock, in the order in which they are allotted funds.
+func (client *client) BudgetProjection(ctx context.Context) ([]Proposal, error) {
+	resp := map[string]proposalResult{}
+	if err := client.Call(ctx, &resp, "mnbudget", "projection"); err != nil {
+		return nil, fmt.Errorf("bad \"mnbudget projection\": %v", err)
+	}
+	results := make([]proposalResult, 0, len(resp))
+	for _, result := range resp {
+		results = append(results, result)
+	}
+	// Proposals with the most net votes are allotted funds first.
+	sort.SliceStable(results, func(i, j int) bool {
+		netI, netJ := results[i].Yeas-results[i].Nays, results[j].Yeas-results[j].Nays
+		if netI != netJ {
+			return netI > netJ
+		}
+		return results[i].Name < results[j].Name
+	})
+	proposals := make([]Proposal, len(results))
+	for i, result := range results {
+		proposal, err := decodeProposal(result)
+		if err != nil {
+			return nil, err
+		}
+		proposals[i] = proposal
+	}
+	return proposals, nil
+}
+
+// ProposalVotes returns the votes cast for, or against, the budget proposal
+// with the given name.
+func (client *client) ProposalVotes(ctx context.Context, name string) ([]ProposalVote, error) {
+	resp := map[string]struct {
+		Hash    string `json:"nHash"`
+		Vote    string `json:"Vote"`
+		Time    int64  `json:"nTime"`
+		IsValid bool   `json:"fValid"`
+	}{}
+	if err := client.Call(ctx, &resp, "mnbudget", "getvotes", name); err != nil {
+		return nil, fmt.Errorf("bad \"mnbudget getvotes\": %v", err)
+	}
+	votes := make([]ProposalVote, 0, len(resp))
+	for key, result := range resp {
+		outpoint, err := decodeOutpoint(key)
+		if err != nil {
+			return nil, fmt.Errorf("bad outpoint: %v", err)
+		}
+		hash, err := decodeHash(result.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("bad vote hash: %v", err)
+		}
+		votes = append(votes, ProposalVote{
+			Outpoint: outpoint,
+			Hash:     hash,
+			Vote:     result.Vote,
+			Time:     result.Time,
+			IsValid:  result.IsValid,
+		})
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		return votes[i].Time < votes[j].Time
+	})
+	return votes, nil
+}
+
+// FinalizedBudgets returns all finalized budgets known by the network, sorted
+// by the block at which they start.
+func (client *client) FinalizedBudgets(ctx context.Context) ([]FinalizedBudget, error) {
+	resp := map[string]struct {
+		FeeTx      string `json:"FeeTX"`
+		Hash       string `json:"Hash"`
+		BlockStart int64  `json:"BlockStart"`
+		BlockEnd   int64  `json:"BlockEnd"`
+		Proposals  string `json:"Proposals"`
+		VoteCount  int64  `json:"VoteCount"`
+		Status     string `json:"Status"`
+	}{}
+	if err := client.Call(ctx, &resp, "mnfinalbudget", "show"); err != nil {
+		return nil, fmt.Errorf("bad \"mnfinalbudget show\": %v", err)
+	}
+	budgets := make([]FinalizedBudget, 0, len(resp))
+	for name, result := range resp {
+		feeTx, err := decodeHash(result.FeeTx)
+		if err != nil {
+			return nil, fmt.Errorf("bad fee tx: %v", err)
+		}
+		hash, err := decodeHash(result.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("bad budget hash: %v", err)
+		}
+		proposals := []string{}
+		for _, proposal := range strings.Split(result.Proposals, ",") {
+			if proposal = strings.TrimSpace(proposal); proposal != "" {
+				proposals = append(proposals, proposal)
+			}
+		}
+		budgets = append(budgets, FinalizedBudget{
+			Name:       name,
+			FeeTx:      feeTx,
+			Hash:       hash,
+			BlockStart: result.BlockStart,
+			BlockEnd:   result.BlockEnd,
+			Proposals:  proposals,
+			VoteCount:  result.VoteCount,
+			Status:     result.Status,
+		})
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		if budgets[i].BlockStart != budgets[j].BlockStart {
+			return budgets[i].BlockStart < budgets[j].BlockStart
+		}
+		return budgets[i].Name < budgets[j].Name
+	})
+	return budgets, nil
+}
+
+func decodeProposals(results []proposalResult) ([]Proposal, error) {
+	proposals := make([]Proposal, len(results))
+	for i, result := range results {
+		proposal, err := decodeProposal(result)
+		if err != nil {
+			return nil, err
+		}
+		proposals[i] = proposal
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		return proposals[i].Name < proposals[j].Name
+	})
+	return proposals, nil
+}
+
+func decodeProposal(result proposalResult) (Proposal, error) {
+	hash, err := decodeHash(result.Hash)
+	if err != nil {
+		return Proposal{}, fmt.Errorf("bad proposal %v: bad hash: %v", result.Name, err)
+	}
+	feeHash, err := decodeHash(result.FeeHash)
+	if err != nil {
+		return Proposal{}, fmt.Errorf("bad proposal %v: bad fee hash: %v", result.Name, err)
+	}
+	amounts := make([]pack.U256, 3)
+	for i, crw := range []float64{result.TotalPayment, result.MonthlyPayment, result.Alloted} {
+		amount, err := btcutil.NewAmount(crw)
+		if err != nil || amount < 0 {
+			return Proposal{}, fmt.Errorf("bad proposal %v: bad amount %v", result.Name, crw)
+		}
+		amounts[i] = pack.NewU256FromU64(pack.NewU64(uint64(amount)))
+	}
+	return Proposal{
+		Name:                  result.Name,
+		URL:                   result.URL,
+		Hash:                  hash,
+		FeeHash:               feeHash,
+		BlockStart:            result.BlockStart,
+		BlockEnd:              result.BlockEnd,
+		TotalPaymentCount:     result.TotalPaymentCount,
+		RemainingPaymentCount: result.RemainingPaymentCount,
+		PaymentAddress:        address.Address(result.PaymentAddress),
+		Yeas:                  result.Yeas,
+		Nays:                  result.Nays,
+		Abstains:              result.Abstains,
+		TotalPayment:          amounts[0],
+		MonthlyPayment:        amounts[1],
+		IsEstablished:         result.IsEstablished,
+		IsValid:               result.IsValid,
+		IsValidReason:         result.IsValidReason,
+		Allotted:              amounts[2],
+	}, nil
+}
+
+// decodeHash from its hex encoding, as returned by the RPC interface. The hash
+// is returned in its internal byte order.
+func decodeHash(str string) (pack.Bytes32, error) {
+	if str == "" {
+		return pack.Bytes32{}, nil
+	}
+	hash, err := chainhash.NewHashFromStr(str)
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	return pack.Bytes32(*hash), nil
+}
+
+// A ProposalSubmission is the content of a budget proposal that is yet to be
+// submitted to the network.
+type ProposalSubmission struct {
+	Name           string
+	URL            string
+	BlockStart     int32
+	BlockEnd       int32
+	PaymentAddress address.Address
+	// MonthlyPayment is the amount paid to the proposal in each superblock.
+	MonthlyPayment pack.U256
+}
+
+// Hash returns the hash that identifies the proposal. It is the double SHA256
+// of the name, URL, start block, end block, monthly payment, and the pubkey
+// script of the payment address, serialized in that order.
+func (submission ProposalSubmission) Hash(params *ChainParams) (pack.Bytes32, error) {
+	if err := verifyRawAddress(base58.Decode(string(submission.PaymentAddress)), params); err != nil {
+		return pack.Bytes32{}, fmt.Errorf("bad payment address %v: %v", submission.PaymentAddress, err)
+	}
+	addr, err := DecodeAddress(string(submission.PaymentAddress))
+	if err != nil {
+		return pack.Bytes32{}, fmt.Errorf("bad payment address %v: %v", submission.PaymentAddress, err)
+	}
+	script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+	if err != nil {
+		return pack.Bytes32{}, fmt.Errorf("bad payment address %v: %v", submission.PaymentAddress, err)
+	}
+	amount := submission.MonthlyPayment.Int()
+	if !amount.IsInt64() {
+		return pack.Bytes32{}, fmt.Errorf("bad monthly payment: %v", amount)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := wire.WriteVarString(buf, 0, submission.Name); err != nil {
+		return pack.Bytes32{}, err
+	}
+	if err := wire.WriteVarString(buf, 0, submission.URL); err != nil {
+		return pack.Bytes32{}, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, submission.BlockStart); err != nil {
+		return pack.Bytes32{}, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, submission.BlockEnd); err != nil {
+		return pack.Bytes32{}, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, amount.Int64()); err != nil {
+		return pack.Bytes32{}, err
+	}
+	if err := wire.WriteVarBytes(buf, 0, script); err != nil {
+		return pack.Bytes32{}, err
+	}
+	return pack.Bytes32(chainhash.DoubleHashH(buf.Bytes())), nil
+}
+
+// BuildProposalTx returns the collateral transaction that must be confirmed
+// before the budget proposal can be submitted. It burns the ProposalFee in an
+// output that commits to the hash of the proposal, and sends the rest of the
+// funds to the given recipients (usually, the change address). The fee paid to
+// the Crown network is the difference between the value of the inputs and the
+// value of the outputs.
+func (txBuilder TxBuilder) BuildProposalTx(inputs []utxo.Input, submission ProposalSubmission, recipients []utxo.Recipient) (utxo.Tx, error) {
+	hash, err := submission.Hash(txBuilder.params)
+	if err != nil {
+		return nil, fmt.Errorf("bad proposal: %v", err)
+	}
+	script, err := txscript.NullDataScript(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	tx, err := txBuilder.BuildTx(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	tx.(*Tx).msgTx.AddTxOut(wire.NewTxOut(ProposalFee, script))
+	return tx, nil
+}
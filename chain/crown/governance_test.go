@@ -0,0 +1,214 @@
+package crown_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Governance", func() {
+	hash1 := strings.Repeat("aa", 32)
+	hash2 := strings.Repeat("bb", 32)
+	newHash := func(str string) pack.Bytes32 {
+		hash, err := chainhash.NewHashFromStr(str)
+		Expect(err).ToNot(HaveOccurred())
+		return pack.Bytes32(*hash)
+	}
+	crw := func(amount uint64) pack.U256 {
+		return pack.NewU256FromU64(pack.NewU64(amount * btcutil.SatoshiPerBitcoin))
+	}
+	proposal := func(name string, yeas, nays int64) map[string]interface{} {
+		return map[string]interface{}{
+			"Name":                  name,
+			"URL":                   "https://crowncoin.org/" + name,
+			"Hash":                  hash1,
+			"FeeHash":               hash2,
+			"BlockStart":            86400,
+			"BlockEnd":              172801,
+			"TotalPaymentCount":     2,
+			"RemainingPaymentCount": 2,
+			"PaymentAddress":        "CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6",
+			"Yeas":                  yeas,
+			"Nays":                  nays,
+			"Abstains":              0,
+			"TotalPayment":          2000.0,
+			"MonthlyPayment":        1000.0,
+			"IsEstablished":         true,
+			"IsValid":               true,
+			"IsValidReason":         "",
+			"Alloted":               1000.0,
+		}
+	}
+	results := map[string]interface{}{
+		"mnbudget show": map[string]interface{}{
+			"marketing":   proposal("marketing", 10, 2),
+			"development": proposal("development", 20, 1),
+		},
+		"mnbudget projection": map[string]interface{}{
+			"marketing":   proposal("marketing", 10, 2),
+			"development": proposal("development", 20, 1),
+		},
+		"mnbudget getvotes": map[string]interface{}{
+			strings.Repeat("11", 32) + "-0": map[string]interface{}{"nHash": hash1, "Vote": "YES", "nTime": 200, "fValid": true},
+			strings.Repeat("22", 32) + "-1": map[string]interface{}{"nHash": hash2, "Vote": "NO", "nTime": 100, "fValid": true},
+		},
+		"mnfinalbudget show": map[string]interface{}{
+			"main": map[string]interface{}{
+				"FeeTX":      hash2,
+				"Hash":       hash1,
+				"BlockStart": 86400,
+				"BlockEnd":   86400,
+				"Proposals":  "development, marketing",
+				"VoteCount":  15,
+				"Status":     "OK",
+			},
+		},
+	}
+
+	Context("when listing proposals", func() {
+		It("should decode the proposals", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			proposals, err := client.Proposals(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proposals).To(HaveLen(2))
+			Expect(proposals[0]).To(Equal(crown.Proposal{
+				Name:                  "development",
+				URL:                   "https://crowncoin.org/development",
+				Hash:                  newHash(hash1),
+				FeeHash:               newHash(hash2),
+				BlockStart:            86400,
+				BlockEnd:              172801,
+				TotalPaymentCount:     2,
+				RemainingPaymentCount: 2,
+				PaymentAddress:        address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"),
+				Yeas:                  20,
+				Nays:                  1,
+				TotalPayment:          crw(2000),
+				MonthlyPayment:        crw(1000),
+				IsEstablished:         true,
+				IsValid:               true,
+				Allotted:              crw(1000),
+			}))
+			Expect(proposals[1].Name).To(Equal("marketing"))
+			Expect(proposals[1].PaymentHeights(crown.MainNetBudgetCycleBlocks)).To(Equal([]int64{86400, 129600}))
+		})
+
+		It("should order the projection by net votes", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			proposals, err := client.BudgetProjection(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proposals).To(HaveLen(2))
+			Expect(proposals[0].Name).To(Equal("development"))
+			Expect(proposals[1].Name).To(Equal("marketing"))
+		})
+	})
+
+	Context("when listing votes", func() {
+		It("should decode the votes in order of time", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			votes, err := client.ProposalVotes(context.Background(), "development")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(votes).To(HaveLen(2))
+			Expect(votes[0].Vote).To(Equal("NO"))
+			Expect(votes[0].Hash).To(Equal(newHash(hash2)))
+			Expect(votes[0].Outpoint.Index).To(Equal(pack.NewU32(1)))
+			Expect(votes[1].Vote).To(Equal("YES"))
+		})
+	})
+
+	Context("when listing finalized budgets", func() {
+		It("should decode the budgets", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			budgets, err := client.FinalizedBudgets(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(budgets).To(Equal([]crown.FinalizedBudget{{
+				Name:       "main",
+				FeeTx:      newHash(hash2),
+				Hash:       newHash(hash1),
+				BlockStart: 86400,
+				BlockEnd:   86400,
+				Proposals:  []string{"development", "marketing"},
+				VoteCount:  15,
+				Status:     "OK",
+			}}))
+		})
+	})
+
+	Context("when building the collateral transaction of a proposal", func() {
+		params := &crown.RegressionNetParams
+		addr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), params)
+		Expect(err).ToNot(HaveOccurred())
+		submission := crown.ProposalSubmission{
+			Name:           "development",
+			URL:            "https://crowncoin.org/development",
+			BlockStart:     86400,
+			BlockEnd:       172801,
+			PaymentAddress: address.Address(addr.EncodeAddress()),
+			MonthlyPayment: crw(1000),
+		}
+
+		It("should burn the fee in an output that commits to the proposal", func() {
+			script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			buf := new(bytes.Buffer)
+			Expect(wire.WriteVarString(buf, 0, submission.Name)).To(Succeed())
+			Expect(wire.WriteVarString(buf, 0, submission.URL)).To(Succeed())
+			Expect(binary.Write(buf, binary.LittleEndian, []int32{86400, 172801})).To(Succeed())
+			Expect(binary.Write(buf, binary.LittleEndian, int64(1000*btcutil.SatoshiPerBitcoin))).To(Succeed())
+			Expect(wire.WriteVarBytes(buf, 0, script)).To(Succeed())
+			expectedHash := chainhash.DoubleHashH(buf.Bytes())
+
+			hash, err := submission.Hash(params)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(pack.Bytes32(expectedHash)))
+
+			inputs := []utxo.Input{{Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x02}, 32)), Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(script),
+				Value:        crw(20),
+			}}}
+			recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(999000000))}}
+			tx, err := crown.NewTxBuilder(params).BuildProposalTx(inputs, submission, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(2))
+			Expect(outputs[1].Value).To(Equal(crw(10)))
+			Expect(outputs[1].PubKeyScript).To(Equal(pack.Bytes(append([]byte{txscript.OP_RETURN, 0x20}, expectedHash[:]...))))
+		})
+
+		It("should return an error for a payment address of a different network", func() {
+			otherAddr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), &crown.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			invalid := submission
+			invalid.PaymentAddress = address.Address(otherAddr.EncodeAddress())
+			_, err = crown.NewTxBuilder(params).BuildProposalTx(nil, invalid, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,76 @@
+package crown
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// InstantLocked returns whether or not the transaction has been locked by
+// InstantSend. Locked transactions cannot be double-spent, even before they
+// have been included in a block. The transaction does not need to belong to the
+// wallet of the node, but the node must be able to find it (it must be in the
+// mempool, or the node must index all transactions).
+func (client *client) InstantLocked(ctx context.Context, txHash pack.Bytes) (bool, error) {
+	status, err := client.lockStatus(ctx, txHash)
+	if err != nil {
+		return false, err
+	}
+	return status.InstantLock, nil
+}
+
+// Final returns whether or not the transaction is final: either it has been
+// locked by InstantSend, or it has at least the given number of confirmations.
+func (client *client) Final(ctx context.Context, txHash pack.Bytes, minConf int64) (bool, error) {
+	status, err := client.lockStatus(ctx, txHash)
+	if err != nil {
+		return false, err
+	}
+	return status.InstantLock || status.Confirmations >= minConf, nil
+}
+
+type lockStatus struct {
+	Confirmations int64 `json:"confirmations"`
+	InstantLock   bool  `json:"instantlock"`
+}
+
+// lockStatus of any transaction known by the node, using the verbose output of
+// "getrawtransaction" (unlike "gettransaction", this is not restricted to
+// transactions in the wallet of the node).
+func (client *client) lockStatus(ctx context.Context, txHash pack.Bytes) (lockStatus, error) {
+	status := lockStatus{}
+	if err := client.Call(ctx, &status, "getrawtransaction", encodeTxHash(txHash), 1); err != nil {
+		return lockStatus{}, fmt.Errorf("bad \"getrawtransaction\": %v", err)
+	}
+	return status, nil
+}
+
+// SubmitTxInstant submits a signed transaction to the network, and requests
+// that it is locked by InstantSend. The node rejects the transaction if it is
+// not eligible for InstantSend (for example, because its inputs do not have
+// enough confirmations).
+func (client *client) SubmitTxInstant(ctx context.Context, tx utxo.Tx) error {
+	serial, err := tx.Serialize()
+	if err != nil {
+		return fmt.Errorf("bad tx: %v", err)
+	}
+	resp := ""
+	if err := client.Call(ctx, &resp, "sendrawtransaction", hex.EncodeToString(serial), false, true); err != nil {
+		return fmt.Errorf("bad \"sendrawtransaction\": %v", err)
+	}
+	return nil
+}
+
+// encodeTxHash as hex, in the reversed byte order expected by the RPC
+// interface.
+func encodeTxHash(txHash pack.Bytes) string {
+	size := len(txHash)
+	txHashReversed := make([]byte, size)
+	for i := range txHash {
+		txHashReversed[size-1-i] = txHash[i]
+	}
+	return hex.EncodeToString(txHashReversed)
+}
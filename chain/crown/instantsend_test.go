@@ -0,0 +1,111 @@
+package crown_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InstantSend", func() {
+	txHash := pack.Bytes(append(bytes.Repeat([]byte{0x01}, 31), 0x02))
+	txHashHex := "02" + hex.EncodeToString(bytes.Repeat([]byte{0x01}, 31))
+
+	Context("when checking the lock status of a transaction", func() {
+		It("should report locked transactions as final", func() {
+			server := newStandIn(map[string]interface{}{
+				"getrawtransaction " + txHashHex: map[string]interface{}{"confirmations": 0, "instantlock": true},
+			})
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			locked, err := client.InstantLocked(context.Background(), txHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(locked).To(BeTrue())
+			final, err := client.Final(context.Background(), txHash, 6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(final).To(BeTrue())
+		})
+
+		It("should only report unlocked transactions as final once they are confirmed", func() {
+			server := newStandIn(map[string]interface{}{
+				"getrawtransaction " + txHashHex: map[string]interface{}{"confirmations": 2, "instantlock": false},
+			})
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			locked, err := client.InstantLocked(context.Background(), txHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(locked).To(BeFalse())
+			final, err := client.Final(context.Background(), txHash, 6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(final).To(BeFalse())
+			final, err = client.Final(context.Background(), txHash, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(final).To(BeTrue())
+		})
+	})
+
+	Context("when the node cannot find the transaction", func() {
+		It("should return an error", func() {
+			server := newStandIn(map[string]interface{}{
+				"getrawtransaction " + txHashHex: btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "No such mempool or blockchain transaction"),
+			})
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			_, err := client.InstantLocked(context.Background(), txHash)
+			Expect(err).To(HaveOccurred())
+			_, err = client.Final(context.Background(), txHash, 6)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when submitting a transaction with InstantSend", func() {
+		It("should request an instant lock", func() {
+			params := []interface{}{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := struct {
+					Method string        `json:"method"`
+					Params []interface{} `json:"params"`
+				}{}
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				Expect(req.Method).To(Equal("sendrawtransaction"))
+				params = req.Params
+				Expect(json.NewEncoder(w).Encode(map[string]interface{}{"result": txHashHex, "error": nil, "id": 1})).To(Succeed())
+			}))
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			addr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), &crown.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			inputs := []utxo.Input{{Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: txHash, Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(script),
+				Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+			}}}
+			recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(90000))}}
+			tx, err := crown.NewTxBuilder(&crown.RegressionNetParams).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(client.SubmitTxInstant(context.Background(), tx)).To(Succeed())
+			Expect(params).To(Equal([]interface{}{hex.EncodeToString(serial), false, true}))
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package crown
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// NewMultisigRedeemScript returns the redeem script of an m-of-n multisig, where
+// m is the threshold and n is the number of public keys. The public keys are
+// used in the given order, so all parties must agree on the order in order to
+// agree on the address.
+func NewMultisigRedeemScript(threshold int, pubKeys []pack.Bytes, params *ChainParams) (pack.Bytes, error) {
+	if len(pubKeys) == 0 || len(pubKeys) > 16 {
+		return nil, fmt.Errorf("expected between 1 and 16 public keys, got %v", len(pubKeys))
+	}
+	if threshold <= 0 || threshold > len(pubKeys) {
+		return nil, fmt.Errorf("expected threshold between 1 and %v, got %v", len(pubKeys), threshold)
+	}
+	addrs := make([]*btcutil.AddressPubKey, len(pubKeys))
+	for i := range pubKeys {
+		addr, err := btcutil.NewAddressPubKey(pubKeys[i], params.Params)
+		if err != nil {
+			return nil, fmt.Errorf("bad public key %v: %v", i, err)
+		}
+		addrs[i] = addr
+	}
+	script, err := txscript.MultiSigScript(addrs, threshold)
+	if err != nil {
+		return nil, err
+	}
+	return pack.NewBytes(script), nil
+}
+
+// NewAddressMultisig returns the P2SH address of an m-of-n multisig, where m is
+// the threshold and n is the number of public keys. Outputs sent to this
+// address are spent by inputs that use the redeem script (returned by
+// NewMultisigRedeemScript) as their sig script, and are signed using
+// Tx.SignMultisig.
+func NewAddressMultisig(threshold int, pubKeys []pack.Bytes, params *ChainParams) (AddressScriptHash, error) {
+	script, err := NewMultisigRedeemScript(threshold, pubKeys, params)
+	if err != nil {
+		return AddressScriptHash{}, err
+	}
+	return NewAddressScriptHash(script, params)
+}
+
+// SignMultisig signs the inputs of a transaction that spend outputs sent to
+// multisig addresses. The signatures for each input can be given in any order,
+// and can include more signatures than are required; they are matched against
+// the public keys in the redeem script of the input, and the required number
+// of signatures are placed in the same order as their public keys. The redeem
+// script must hash to the P2SH pubkey script of the input.
+//
+// Inputs that do not spend multisig outputs must be given an empty set of
+// signatures, and are left unsigned so that they can be signed using Sign
+// afterwards.
+func (tx *Tx) SignMultisig(signatures [][]pack.Bytes65) error {
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if len(signatures) != len(tx.msgTx.TxIn) {
+		return fmt.Errorf("expected %v signatures, got %v signatures", len(tx.msgTx.TxIn), len(signatures))
+	}
+	sighashes, err := tx.Sighashes()
+	if err != nil {
+		return err
+	}
+
+	sigScripts := make([][]byte, len(signatures))
+	for i := range signatures {
+		redeemScript := tx.inputs[i].SigScript
+		class, addrs, threshold, err := txscript.ExtractPkScriptAddrs(redeemScript, tx.params.Params)
+		isMultisig := err == nil && class == txscript.MultiSigTy
+		if len(signatures[i]) == 0 {
+			if isMultisig {
+				return fmt.Errorf("bad input %v: expected signatures", i)
+			}
+			continue
+		}
+		if !isMultisig {
+			return fmt.Errorf("bad input %v: expected multisig redeem script", i)
+		}
+		if err := verifyRedeemScript(tx.inputs[i].PubKeyScript, redeemScript); err != nil {
+			return fmt.Errorf("bad input %v: %v", i, err)
+		}
+
+		// Find the public key that produced each signature.
+		type indexedSignature struct {
+			index     int
+			signature *btcec.Signature
+		}
+		found := map[int]bool{}
+		ordered := []indexedSignature{}
+		for _, rsv := range signatures[i] {
+			signature := &btcec.Signature{
+				R: new(big.Int).SetBytes(rsv[:32]),
+				S: new(big.Int).SetBytes(rsv[32:64]),
+			}
+			for j, addr := range addrs {
+				pubKey := addr.(*btcutil.AddressPubKey).PubKey()
+				if !found[j] && signature.Verify(sighashes[i][:], pubKey) {
+					found[j] = true
+					ordered = append(ordered, indexedSignature{index: j, signature: signature})
+					break
+				}
+			}
+		}
+		if len(ordered) < threshold {
+			return fmt.Errorf("bad input %v: expected %v valid signatures, got %v", i, threshold, len(ordered))
+		}
+		sort.Slice(ordered, func(a, b int) bool {
+			return ordered[a].index < ordered[b].index
+		})
+
+		// OP_CHECKMULTISIG pops one more item than it needs, so the script
+		// starts with OP_0.
+		builder := txscript.NewScriptBuilder()
+		builder.AddOp(txscript.OP_0)
+		for _, s := range ordered[:threshold] {
+			builder.AddData(append(s.signature.Serialize(), byte(txscript.SigHashAll)))
+		}
+		builder.AddData(redeemScript)
+		if sigScripts[i], err = builder.Script(); err != nil {
+			return err
+		}
+	}
+
+	signed := true
+	for i := range sigScripts {
+		if sigScripts[i] == nil {
+			signed = false
+			continue
+		}
+		tx.msgTx.TxIn[i].SignatureScript = sigScripts[i]
+	}
+	tx.signed = signed
+	return nil
+}
+
+// verifyRedeemScript checks that the pubkey script is a P2SH script for the
+// redeem script.
+func verifyRedeemScript(pubKeyScript, redeemScript pack.Bytes) error {
+	if utxo.ScriptTypeOf(pubKeyScript) != utxo.ScriptTypeP2SH {
+		return fmt.Errorf("expected P2SH pubkey script")
+	}
+	if !bytes.Equal(pubKeyScript[2:22], btcutil.Hash160(redeemScript)) {
+		return fmt.Errorf("redeem script does not match pubkey script")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package crown_test
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multisig", func() {
+	params := &crown.RegressionNetParams
+	privKeys := make([]*btcec.PrivateKey, 3)
+	pubKeys := make([]pack.Bytes, 3)
+	for i := range privKeys {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).ToNot(HaveOccurred())
+		privKeys[i] = privKey
+		pubKeys[i] = pack.NewBytes(privKey.PubKey().SerializeCompressed())
+	}
+
+	redeemScript, err := crown.NewMultisigRedeemScript(2, pubKeys, params)
+	Expect(err).ToNot(HaveOccurred())
+	addr, err := crown.NewAddressMultisig(2, pubKeys, params)
+	Expect(err).ToNot(HaveOccurred())
+	pubKeyScript, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+	Expect(err).ToNot(HaveOccurred())
+
+	buildTx := func() *crown.Tx {
+		inputs := []utxo.Input{{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(pubKeyScript),
+				Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+			},
+			SigScript: redeemScript,
+		}}
+		recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(90000))}}
+		tx, err := crown.NewTxBuilder(params).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*crown.Tx)
+	}
+	sign := func(tx *crown.Tx, privKey *btcec.PrivateKey) pack.Bytes65 {
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		signature, err := privKey.Sign(sighashes[0][:])
+		Expect(err).ToNot(HaveOccurred())
+		rsv := pack.Bytes65{}
+		copy(rsv[:32], pack.NewU256FromInt(signature.R).Bytes())
+		copy(rsv[32:64], pack.NewU256FromInt(signature.S).Bytes())
+		return rsv
+	}
+
+	Context("when creating a multisig address", func() {
+		It("should return an error for a bad threshold", func() {
+			_, err := crown.NewAddressMultisig(0, pubKeys, params)
+			Expect(err).To(HaveOccurred())
+			_, err = crown.NewAddressMultisig(4, pubKeys, params)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error for a bad public key", func() {
+			_, err := crown.NewAddressMultisig(1, []pack.Bytes{pack.Bytes{0x02, 0x01}}, params)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when spending from a 2-of-3 multisig", func() {
+		It("should produce a valid script from signatures in any order", func() {
+			tx := buildTx()
+			// The signature from the third key comes first, and there is an
+			// extra signature from an unrelated key.
+			otherKey, err := btcec.NewPrivateKey(btcec.S256())
+			Expect(err).ToNot(HaveOccurred())
+			signatures := []pack.Bytes65{sign(tx, privKeys[2]), sign(tx, otherKey), sign(tx, privKeys[0])}
+			Expect(tx.SignMultisig([][]pack.Bytes65{signatures})).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(crown.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(serial))).To(Succeed())
+			engine, err := txscript.NewEngine(pubKeyScript, msgTx, 0, txscript.StandardVerifyFlags, nil, nil, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(engine.Execute()).To(Succeed())
+
+			// The fee estimate must cover the signed transaction. It assumes
+			// that both signatures have the maximum length of 72 bytes, and
+			// DER signatures are rarely shorter than 68 bytes.
+			fee, err := crown.Fee(buildTx(), pack.NewU256FromU64(pack.NewU64(1)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee.Int().Int64()).To(BeNumerically(">=", len(serial)))
+			Expect(fee.Int().Int64()).To(BeNumerically("<=", len(serial)+2*(72-68)))
+		})
+
+		It("should return an error when there are not enough signatures", func() {
+			tx := buildTx()
+			signatures := []pack.Bytes65{sign(tx, privKeys[1]), sign(tx, privKeys[1])}
+			Expect(tx.SignMultisig([][]pack.Bytes65{signatures})).ToNot(Succeed())
+		})
+
+		It("should return an error when the input is not a multisig", func() {
+			tx := buildTx()
+			Expect(tx.SignMultisig([][]pack.Bytes65{{}, {}})).ToNot(Succeed())
+			// Multisig inputs must be signed.
+			Expect(tx.SignMultisig([][]pack.Bytes65{{}})).ToNot(Succeed())
+		})
+
+		It("should return an error when the redeem script does not match", func() {
+			otherScript, err := crown.NewMultisigRedeemScript(1, pubKeys, params)
+			Expect(err).ToNot(HaveOccurred())
+			inputs := []utxo.Input{{
+				Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+					PubKeyScript: pack.Bytes(pubKeyScript),
+					Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+				},
+				SigScript: otherScript,
+			}}
+			recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(90000))}}
+			tx, err := crown.NewTxBuilder(params).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			signature := sign(tx.(*crown.Tx), privKeys[0])
+			Expect(tx.(*crown.Tx).SignMultisig([][]pack.Bytes65{{signature}})).ToNot(Succeed())
+		})
+	})
+
+	Context("when spending from a multisig and a P2PKH address", func() {
+		It("should sign the P2PKH input using Sign", func() {
+			pkhPubKey := privKeys[0].PubKey().SerializeCompressed()
+			pkhAddr, err := crown.NewAddressPubKeyHash(btcutil.Hash160(pkhPubKey), params)
+			Expect(err).ToNot(HaveOccurred())
+			pkhScript, err := txscript.PayToAddrScript(pkhAddr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			inputs := []utxo.Input{
+				{
+					Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+						PubKeyScript: pack.Bytes(pubKeyScript),
+						Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+					},
+					SigScript: redeemScript,
+				},
+				{
+					Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x02}, 32)), Index: pack.NewU32(0)},
+						PubKeyScript: pack.Bytes(pkhScript),
+						Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+					},
+				},
+			}
+			recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(190000))}}
+			utxoTx, err := crown.NewTxBuilder(params).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			tx := utxoTx.(*crown.Tx)
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			signDigest := func(privKey *btcec.PrivateKey, digest pack.Bytes32) pack.Bytes65 {
+				signature, err := privKey.Sign(digest[:])
+				Expect(err).ToNot(HaveOccurred())
+				rsv := pack.Bytes65{}
+				copy(rsv[:32], pack.NewU256FromInt(signature.R).Bytes())
+				copy(rsv[32:64], pack.NewU256FromInt(signature.S).Bytes())
+				return rsv
+			}
+			Expect(tx.SignMultisig([][]pack.Bytes65{
+				{signDigest(privKeys[0], sighashes[0]), signDigest(privKeys[1], sighashes[0])},
+				{},
+			})).To(Succeed())
+			Expect(tx.Sign([]pack.Bytes65{{}, signDigest(privKeys[0], sighashes[1])}, pack.NewBytes(pkhPubKey))).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(crown.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(serial))).To(Succeed())
+			for i, script := range [][]byte{pubKeyScript, pkhScript} {
+				engine, err := txscript.NewEngine(script, msgTx, i, txscript.StandardVerifyFlags, nil, nil, 100000)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(engine.Execute()).To(Succeed())
+			}
+		})
+	})
+})
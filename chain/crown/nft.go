@@ -0,0 +1,488 @@
+package crown
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// The minimum and maximum lengths of the identifier of an NFT protocol.
+const (
+	MinNFTProtocolIDLength = 3
+	MaxNFTProtocolIDLength = 12
+)
+
+// An NFTRegSign defines who must sign the registration of tokens that belong to
+// an NFT protocol.
+type NFTRegSign int64
+
+// Enumerate the NFT registration signing schemes.
+const (
+	// NFTRegSignSelf means that tokens are signed by their owner.
+	NFTRegSignSelf = NFTRegSign(1)
+	// NFTRegSignCreator means that tokens are signed by the owner of the
+	// protocol.
+	NFTRegSignCreator = NFTRegSign(2)
+	// NFTRegSignPayer means that tokens are signed by the payer of the
+	// registration transaction.
+	NFTRegSignPayer = NFTRegSign(3)
+)
+
+// An NFTProtocol is an NFT protocol that has been registered on-chain.
+type NFTProtocol struct {
+	BlockHash          pack.Bytes32
+	RegistrationTxHash pack.Bytes32
+	Height             int64
+	Timestamp          int64
+
+	ID                  string
+	Name                string
+	Owner               address.Address
+	RegSign             NFTRegSign
+	MetadataMimeType    string
+	MetadataSchemaURI   string
+	IsTokenTransferable bool
+	IsMetadataEmbedded  bool
+	MaxMetadataSize     int64
+}
+
+// An NFT is a token that has been registered on-chain.
+type NFT struct {
+	BlockHash          pack.Bytes32
+	RegistrationTxHash pack.Bytes32
+	Height             int64
+	Timestamp          int64
+
+	ProtocolID string
+	// ID of the token, in the byte order of its serialization. Like hashes,
+	// the RPC interface displays identifiers in the reversed byte order.
+	ID            pack.Bytes32
+	Owner         address.Address
+	MetadataAdmin address.Address
+	// Metadata of the token. If the metadata of the protocol is not embedded,
+	// then this is a URI from which the metadata can be fetched.
+	Metadata string
+}
+
+// An NFTProtocolRegistration is an NFT protocol that is yet to be registered.
+type NFTProtocolRegistration struct {
+	ID                  string
+	Name                string
+	Owner               address.Address
+	RegSign             NFTRegSign
+	MetadataMimeType    string
+	MetadataSchemaURI   string
+	IsTokenTransferable bool
+	IsMetadataEmbedded  bool
+	MaxMetadataSize     int64
+}
+
+// An NFTIssuance is a token that is yet to be registered. The identifier uses
+// the same byte order as NFT.ID.
+type NFTIssuance struct {
+	ProtocolID    string
+	ID            pack.Bytes32
+	Owner         address.Address
+	MetadataAdmin address.Address
+	Metadata      string
+}
+
+type nftProtocolResult struct {
+	BlockHash           string `json:"blockHash"`
+	RegistrationTxHash  string `json:"registrationTxHash"`
+	Height              int64  `json:"height"`
+	Timestamp           int64  `json:"timestamp"`
+	ID                  string `json:"nftProtocolId"`
+	Name                string `json:"tokenProtocolName"`
+	Owner               string `json:"tokenProtocolOwnerId"`
+	RegSign             int64  `json:"nftRegSign"`
+	MetadataMimeType    string `json:"tokenMetadataMimeType"`
+	MetadataSchemaURI   string `json:"tokenMetadataSchemaUri"`
+	IsTokenTransferable bool   `json:"isTokenTransferable"`
+	IsMetadataEmbedded  bool   `json:"isMetadataEmbedded"`
+	MaxMetadataSize     int64  `json:"maxMetadataSize"`
+}
+
+type nftResult struct {
+	BlockHash          string `json:"blockHash"`
+	RegistrationTxHash string `json:"registrationTxHash"`
+	Height             int64  `json:"height"`
+	Timestamp          int64  `json:"timestamp"`
+	ProtocolID         string `json:"nftProtocolId"`
+	ID                 string `json:"nftId"`
+	Owner              string `json:"nftOwnerKeyId"`
+	MetadataAdmin      string `json:"metadataAdminKeyId"`
+	Metadata           string `json:"metadata"`
+}
+
+// NFTProtocols returns the NFT protocols that have been registered, starting
+// with the most recent. At most count protocols are returned, after skipping
+// the given number of protocols.
+func (client *client) NFTProtocols(ctx context.Context, count, skip int64) ([]NFTProtocol, error) {
+	resp := []nftProtocolResult{}
+	if err := client.Call(ctx, &resp, "nftproto", "list", count, skip); err != nil {
+		return nil, fmt.Errorf("bad \"nftproto list\": %v", err)
+	}
+	protocols := make([]NFTProtocol, len(resp))
+	for i := range resp {
+		protocol, err := decodeNFTProtocol(resp[i])
+		if err != nil {
+			return nil, err
+		}
+		protocols[i] = protocol
+	}
+	return protocols, nil
+}
+
+// NFTProtocol returns the NFT protocol with the given identifier.
+func (client *client) NFTProtocol(ctx context.Context, id string) (NFTProtocol, error) {
+	resp := nftProtocolResult{}
+	if err := client.Call(ctx, &resp, "nftproto", "get", id); err != nil {
+		return NFTProtocol{}, fmt.Errorf("bad \"nftproto get\": %v", err)
+	}
+	return decodeNFTProtocol(resp)
+}
+
+// NFTs returns the tokens that have been registered, starting with the most
+// recent. Tokens can be filtered by their protocol and their owner, and empty
+// filters match all tokens. At most count tokens are returned, after skipping
+// the given number of tokens.
+func (client *client) NFTs(ctx context.Context, protocolID string, owner address.Address, count, skip int64) ([]NFT, error) {
+	if protocolID == "" {
+		protocolID = "*"
+	}
+	if owner == "" {
+		owner = "*"
+	}
+	resp := []nftResult{}
+	if err := client.Call(ctx, &resp, "nft", "list", count, skip, "*", protocolID, string(owner)); err != nil {
+		return nil, fmt.Errorf("bad \"nft list\": %v", err)
+	}
+	nfts := make([]NFT, len(resp))
+	for i := range resp {
+		nft, err := decodeNFT(resp[i])
+		if err != nil {
+			return nil, err
+		}
+		nfts[i] = nft
+	}
+	return nfts, nil
+}
+
+// NFT returns the token with the given protocol and identifier, including its
+// metadata.
+func (client *client) NFT(ctx context.Context, protocolID string, id pack.Bytes32) (NFT, error) {
+	resp := nftResult{}
+	if err := client.Call(ctx, &resp, "nft", "get", protocolID, encodeNFTID(id)); err != nil {
+		return NFT{}, fmt.Errorf("bad \"nft get\": %v", err)
+	}
+	return decodeNFT(resp)
+}
+
+// NFTOwner returns the owner of the token with the given protocol and
+// identifier.
+func (client *client) NFTOwner(ctx context.Context, protocolID string, id pack.Bytes32) (address.Address, error) {
+	var resp string
+	if err := client.Call(ctx, &resp, "nft", "ownerof", protocolID, encodeNFTID(id)); err != nil {
+		return "", fmt.Errorf("bad \"nft ownerof\": %v", err)
+	}
+	return address.Address(resp), nil
+}
+
+// RegisterNFTProtocolWithWallet is a convenience wrapper that registers an NFT
+// protocol using the wallet of the Crown node, and returns the hash of the
+// registration transaction. The node must hold the keys of the owner. Use
+// TxBuilder.BuildNFTProtocolRegistrationTx to build the registration without
+// the wallet.
+func (client *client) RegisterNFTProtocolWithWallet(ctx context.Context, registration NFTProtocolRegistration) (pack.Bytes, error) {
+	if err := registration.verify(); err != nil {
+		return nil, err
+	}
+	var resp string
+	if err := client.Call(ctx, &resp, "nftproto", "register",
+		registration.ID,
+		registration.Name,
+		string(registration.Owner),
+		int64(registration.RegSign),
+		registration.MetadataMimeType,
+		registration.MetadataSchemaURI,
+		registration.IsTokenTransferable,
+		registration.IsMetadataEmbedded,
+		registration.MaxMetadataSize,
+	); err != nil {
+		return nil, fmt.Errorf("bad \"nftproto register\": %v", err)
+	}
+	return decodeTxHash(resp)
+}
+
+// IssueNFTWithWallet is a convenience wrapper that registers a token using the
+// wallet of the Crown node, and returns the hash of the registration
+// transaction. The node must hold the keys required by the protocol. Use
+// TxBuilder.BuildNFTIssuanceTx to build the registration without the wallet.
+func (client *client) IssueNFTWithWallet(ctx context.Context, issuance NFTIssuance) (pack.Bytes, error) {
+	if err := verifyNFTProtocolID(issuance.ProtocolID); err != nil {
+		return nil, fmt.Errorf("bad protocol id: %v", err)
+	}
+	var resp string
+	if err := client.Call(ctx, &resp, "nft", "issue",
+		issuance.ProtocolID,
+		encodeNFTID(issuance.ID),
+		string(issuance.Owner),
+		string(issuance.MetadataAdmin),
+		issuance.Metadata,
+	); err != nil {
+		return nil, fmt.Errorf("bad \"nft issue\": %v", err)
+	}
+	return decodeTxHash(resp)
+}
+
+// BuildNFTProtocolRegistrationTx returns a special transaction that registers
+// an NFT protocol. The inputs pay the registration fee, and the recipients
+// receive the change. The payload must be signed by the owner of the protocol
+// (see Tx.PayloadSighash and Tx.SignPayload) before the inputs are signed.
+func (txBuilder TxBuilder) BuildNFTProtocolRegistrationTx(inputs []utxo.Input, recipients []utxo.Recipient, registration NFTProtocolRegistration) (*Tx, error) {
+	payload, err := registration.encode()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := txBuilder.BuildTx(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	return newSpecialTx(tx.(*Tx), SpecialTxTypeNFTProtocolRegister, payload), nil
+}
+
+// BuildNFTIssuanceTx returns a special transaction that registers a token. The
+// inputs pay the registration fee, and the recipients receive the change. The
+// payload must be signed by the key required by the registration signing
+// scheme of the protocol (see Tx.PayloadSighash and Tx.SignPayload) before the
+// inputs are signed.
+func (txBuilder TxBuilder) BuildNFTIssuanceTx(inputs []utxo.Input, recipients []utxo.Recipient, issuance NFTIssuance) (*Tx, error) {
+	payload, err := issuance.encode()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := txBuilder.BuildTx(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	return newSpecialTx(tx.(*Tx), SpecialTxTypeNFTRegister, payload), nil
+}
+
+// nftPayloadVersion is the version of the NFT registration payloads.
+const nftPayloadVersion = 1
+
+func (registration NFTProtocolRegistration) verify() error {
+	if err := verifyNFTProtocolID(registration.ID); err != nil {
+		return fmt.Errorf("bad protocol id: %v", err)
+	}
+	switch registration.RegSign {
+	case NFTRegSignSelf, NFTRegSignCreator, NFTRegSignPayer:
+	default:
+		return fmt.Errorf("bad registration signing scheme: %v", registration.RegSign)
+	}
+	if registration.MaxMetadataSize < 0 || registration.MaxMetadataSize > 255 {
+		return fmt.Errorf("expected max metadata size between 0 and 255, got %v", registration.MaxMetadataSize)
+	}
+	return nil
+}
+
+// encode the registration as the payload of a special transaction, without
+// its signature.
+func (registration NFTProtocolRegistration) encode() ([]byte, error) {
+	if err := registration.verify(); err != nil {
+		return nil, err
+	}
+	owner, err := decodeKeyID(registration.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("bad owner: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, uint16(nftPayloadVersion)); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, encodeNFTProtocolID(registration.ID)); err != nil {
+		return nil, err
+	}
+	for _, str := range []string{registration.Name, registration.MetadataSchemaURI, registration.MetadataMimeType} {
+		if err := wire.WriteVarString(buf, 0, str); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(buf, binary.LittleEndian, registration.IsTokenTransferable); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, registration.IsMetadataEmbedded); err != nil {
+		return nil, err
+	}
+	buf.WriteByte(byte(registration.RegSign))
+	buf.WriteByte(byte(registration.MaxMetadataSize))
+	buf.Write(owner)
+	return buf.Bytes(), nil
+}
+
+// encode the issuance as the payload of a special transaction, without its
+// signature. The metadata admin is optional.
+func (issuance NFTIssuance) encode() ([]byte, error) {
+	if err := verifyNFTProtocolID(issuance.ProtocolID); err != nil {
+		return nil, fmt.Errorf("bad protocol id: %v", err)
+	}
+	owner, err := decodeKeyID(issuance.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("bad owner: %v", err)
+	}
+	metadataAdmin := make([]byte, 20)
+	if issuance.MetadataAdmin != "" {
+		if metadataAdmin, err = decodeKeyID(issuance.MetadataAdmin); err != nil {
+			return nil, fmt.Errorf("bad metadata admin: %v", err)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, uint16(nftPayloadVersion)); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, encodeNFTProtocolID(issuance.ProtocolID)); err != nil {
+		return nil, err
+	}
+	buf.Write(issuance.ID[:])
+	buf.Write(owner)
+	buf.Write(metadataAdmin)
+	if err := wire.WriteVarBytes(buf, 0, []byte(issuance.Metadata)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeKeyID returns the public key hash of a P2PKH address, which is how
+// owners are identified by NFT payloads.
+func decodeKeyID(addr address.Address) ([]byte, error) {
+	decoded, err := DecodeAddress(string(addr))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := decoded.(AddressPubKeyHash); !ok {
+		return nil, fmt.Errorf("expected P2PKH address, got %v", addr)
+	}
+	return decoded.ScriptAddress(), nil
+}
+
+// nftProtocolIDCharset maps each character allowed in a protocol identifier to
+// its 5-bit value.
+const nftProtocolIDCharset = ".12345abcdefghijklmnopqrstuvwxyz"
+
+// encodeNFTProtocolID packs a protocol identifier into 64 bits, using 5 bits
+// per character starting from the most significant bit. The identifier must
+// have been verified.
+func encodeNFTProtocolID(id string) uint64 {
+	value := uint64(0)
+	for i, c := range id {
+		value |= uint64(strings.IndexRune(nftProtocolIDCharset, c)) << uint(64-5*(i+1))
+	}
+	return value
+}
+
+func decodeNFTProtocol(result nftProtocolResult) (NFTProtocol, error) {
+	blockHash, err := decodeHash(result.BlockHash)
+	if err != nil {
+		return NFTProtocol{}, fmt.Errorf("bad protocol %v: bad block hash: %v", result.ID, err)
+	}
+	txHash, err := decodeHash(result.RegistrationTxHash)
+	if err != nil {
+		return NFTProtocol{}, fmt.Errorf("bad protocol %v: bad registration tx hash: %v", result.ID, err)
+	}
+	return NFTProtocol{
+		BlockHash:           blockHash,
+		RegistrationTxHash:  txHash,
+		Height:              result.Height,
+		Timestamp:           result.Timestamp,
+		ID:                  result.ID,
+		Name:                result.Name,
+		Owner:               address.Address(result.Owner),
+		RegSign:             NFTRegSign(result.RegSign),
+		MetadataMimeType:    result.MetadataMimeType,
+		MetadataSchemaURI:   result.MetadataSchemaURI,
+		IsTokenTransferable: result.IsTokenTransferable,
+		IsMetadataEmbedded:  result.IsMetadataEmbedded,
+		MaxMetadataSize:     result.MaxMetadataSize,
+	}, nil
+}
+
+func decodeNFT(result nftResult) (NFT, error) {
+	blockHash, err := decodeHash(result.BlockHash)
+	if err != nil {
+		return NFT{}, fmt.Errorf("bad token %v: bad block hash: %v", result.ID, err)
+	}
+	txHash, err := decodeHash(result.RegistrationTxHash)
+	if err != nil {
+		return NFT{}, fmt.Errorf("bad token %v: bad registration tx hash: %v", result.ID, err)
+	}
+	id, err := decodeNFTID(result.ID)
+	if err != nil {
+		return NFT{}, fmt.Errorf("bad token id %v: %v", result.ID, err)
+	}
+	return NFT{
+		BlockHash:          blockHash,
+		RegistrationTxHash: txHash,
+		Height:             result.Height,
+		Timestamp:          result.Timestamp,
+		ProtocolID:         result.ProtocolID,
+		ID:                 id,
+		Owner:              address.Address(result.Owner),
+		MetadataAdmin:      address.Address(result.MetadataAdmin),
+		Metadata:           result.Metadata,
+	}, nil
+}
+
+// encodeNFTID as hex, in the reversed byte order expected by the RPC
+// interface. Crown Core parses and displays token identifiers as uint256
+// values, so they are reversed in the same way as hashes.
+func encodeNFTID(id pack.Bytes32) string {
+	return chainhash.Hash(id).String()
+}
+
+// decodeNFTID from its hex encoding, as returned by the RPC interface. The
+// identifier is returned in the byte order of its serialization.
+func decodeNFTID(str string) (pack.Bytes32, error) {
+	if len(str) != 2*chainhash.HashSize {
+		return pack.Bytes32{}, fmt.Errorf("expected %v hex characters, got %v", 2*chainhash.HashSize, len(str))
+	}
+	hash, err := chainhash.NewHashFromStr(str)
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	return pack.Bytes32(*hash), nil
+}
+
+// decodeTxHash from its hex encoding, as returned by the RPC interface. The
+// hash is returned in its internal byte order, which is the same as the hashes
+// returned by Tx.Hash.
+func decodeTxHash(str string) (pack.Bytes, error) {
+	hash, err := decodeHash(str)
+	if err != nil {
+		return nil, fmt.Errorf("bad tx hash: %v", err)
+	}
+	return pack.NewBytes(hash[:]), nil
+}
+
+// verifyNFTProtocolID checks that the identifier is between 3 and 12
+// characters, and only uses the characters allowed by Crown (lowercase letters,
+// the digits 1 to 5, and periods).
+func verifyNFTProtocolID(id string) error {
+	if len(id) < MinNFTProtocolIDLength || len(id) > MaxNFTProtocolIDLength {
+		return fmt.Errorf("expected length between %v and %v, got %v", MinNFTProtocolIDLength, MaxNFTProtocolIDLength, len(id))
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(nftProtocolIDCharset, c) {
+			return fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return nil
+}
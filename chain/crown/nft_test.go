@@ -0,0 +1,340 @@
+package crown_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NFT", func() {
+	blockHash := strings.Repeat("aa", 32)
+	txHash := strings.Repeat("bb", 32)
+	tokenID := "0102030405060708091011121314151617181920212223242526272829303132"
+	newHash := func(str string) pack.Bytes32 {
+		hash, err := chainhash.NewHashFromStr(str)
+		Expect(err).ToNot(HaveOccurred())
+		return pack.Bytes32(*hash)
+	}
+	protocol := map[string]interface{}{
+		"blockHash":              blockHash,
+		"registrationTxHash":     txHash,
+		"height":                 100,
+		"timestamp":              1600000000,
+		"nftProtocolId":          "doc",
+		"tokenProtocolName":      "Documents",
+		"tokenProtocolOwnerId":   "CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6",
+		"nftRegSign":             2,
+		"tokenMetadataMimeType":  "application/json",
+		"tokenMetadataSchemaUri": "https://crowncoin.org/doc.json",
+		"isTokenTransferable":    true,
+		"isMetadataEmbedded":     false,
+		"maxMetadataSize":        128,
+	}
+	token := map[string]interface{}{
+		"blockHash":          blockHash,
+		"registrationTxHash": txHash,
+		"height":             101,
+		"timestamp":          1600000060,
+		"nftProtocolId":      "doc",
+		"nftId":              tokenID,
+		"nftOwnerKeyId":      "CRWRyvXjvQmWDMgxb7YESSVJBVd4gHJQbEK9",
+		"metadataAdminKeyId": "CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6",
+		"metadata":           "https://crowncoin.org/doc/1.json",
+	}
+	results := map[string]interface{}{
+		"nftproto list":     []interface{}{protocol},
+		"nftproto get":      protocol,
+		"nftproto register": txHash,
+		"nft list":          []interface{}{token},
+		"nft get":           token,
+		"nft ownerof":       "CRWRyvXjvQmWDMgxb7YESSVJBVd4gHJQbEK9",
+		"nft issue":         txHash,
+	}
+	expectedToken := crown.NFT{
+		BlockHash:          newHash(blockHash),
+		RegistrationTxHash: newHash(txHash),
+		Height:             101,
+		Timestamp:          1600000060,
+		ProtocolID:         "doc",
+		ID:                 newHash(tokenID),
+		Owner:              address.Address("CRWRyvXjvQmWDMgxb7YESSVJBVd4gHJQbEK9"),
+		MetadataAdmin:      address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"),
+		Metadata:           "https://crowncoin.org/doc/1.json",
+	}
+
+	Context("when reading protocols", func() {
+		It("should decode the protocols", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			protocols, err := client.NFTProtocols(context.Background(), 10, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(protocols).To(Equal([]crown.NFTProtocol{{
+				BlockHash:           newHash(blockHash),
+				RegistrationTxHash:  newHash(txHash),
+				Height:              100,
+				Timestamp:           1600000000,
+				ID:                  "doc",
+				Name:                "Documents",
+				Owner:               address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"),
+				RegSign:             crown.NFTRegSignCreator,
+				MetadataMimeType:    "application/json",
+				MetadataSchemaURI:   "https://crowncoin.org/doc.json",
+				IsTokenTransferable: true,
+				MaxMetadataSize:     128,
+			}}))
+
+			protocol, err := client.NFTProtocol(context.Background(), "doc")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(protocol).To(Equal(protocols[0]))
+		})
+	})
+
+	Context("when reading tokens", func() {
+		It("should decode the tokens and their owners", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			tokens, err := client.NFTs(context.Background(), "doc", "", 10, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokens).To(Equal([]crown.NFT{expectedToken}))
+
+			token, err := client.NFT(context.Background(), "doc", expectedToken.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal(expectedToken))
+
+			owner, err := client.NFTOwner(context.Background(), "doc", expectedToken.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owner).To(Equal(expectedToken.Owner))
+		})
+
+		It("should send identifiers in the reversed byte order", func() {
+			params := [][]interface{}{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := struct {
+					Params []interface{} `json:"params"`
+				}{}
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				params = append(params, req.Params)
+				result := interface{}(token)
+				if req.Params[0] != "get" {
+					result = txHash
+				}
+				Expect(json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil, "id": 1})).To(Succeed())
+			}))
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			_, err := client.NFT(context.Background(), "doc", expectedToken.ID)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.NFTOwner(context.Background(), "doc", expectedToken.ID)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.IssueNFTWithWallet(context.Background(), crown.NFTIssuance{ProtocolID: "doc", ID: expectedToken.ID})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(params).To(HaveLen(3))
+			for _, p := range params {
+				Expect(p[2]).To(Equal(tokenID))
+			}
+		})
+
+		It("should return an error for a truncated identifier", func() {
+			truncated := map[string]interface{}{}
+			for k, v := range token {
+				truncated[k] = v
+			}
+			truncated["nftId"] = tokenID[2:]
+			server := newStandIn(map[string]interface{}{"nft get": truncated})
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+
+			_, err := client.NFT(context.Background(), "doc", expectedToken.ID)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when registering", func() {
+		It("should return the hash of the registration transaction", func() {
+			server := newStandIn(results)
+			defer server.Close()
+			client := crown.NewClient(crown.DefaultClientOptions().WithHost(server.URL))
+			expectedHash := newHash(txHash)
+
+			hash, err := client.RegisterNFTProtocolWithWallet(context.Background(), crown.NFTProtocolRegistration{
+				ID:      "doc",
+				Name:    "Documents",
+				Owner:   address.Address("CRWFZ1TNrFKXrm1ut9kbc1s3SeTd3wKnLVR6"),
+				RegSign: crown.NFTRegSignCreator,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(pack.NewBytes(expectedHash[:])))
+
+			hash, err = client.IssueNFTWithWallet(context.Background(), crown.NFTIssuance{
+				ProtocolID: "doc",
+				ID:         expectedToken.ID,
+				Owner:      expectedToken.Owner,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(pack.NewBytes(expectedHash[:])))
+		})
+
+		It("should return an error for an invalid protocol", func() {
+			client := crown.NewClient(crown.DefaultClientOptions())
+			_, err := client.RegisterNFTProtocolWithWallet(context.Background(), crown.NFTProtocolRegistration{ID: "Documents!", RegSign: crown.NFTRegSignSelf})
+			Expect(err).To(HaveOccurred())
+			_, err = client.RegisterNFTProtocolWithWallet(context.Background(), crown.NFTProtocolRegistration{ID: "doc"})
+			Expect(err).To(HaveOccurred())
+			_, err = client.IssueNFTWithWallet(context.Background(), crown.NFTIssuance{ProtocolID: "averyverylongprotocol"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building registration transactions", func() {
+		params := &crown.TesnetParams
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).ToNot(HaveOccurred())
+		owner, err := crown.NewAddressPubKeyHash(btcutil.Hash160(privKey.PubKey().SerializeCompressed()), params)
+		Expect(err).ToNot(HaveOccurred())
+		pubKeyScript, err := txscript.PayToAddrScript(owner.BitcoinAddress())
+		Expect(err).ToNot(HaveOccurred())
+		inputs := []utxo.Input{{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+				PubKeyScript: pack.Bytes(pubKeyScript),
+				Value:        pack.NewU256FromU64(pack.NewU64(100000000)),
+			},
+		}}
+		recipients := []utxo.Recipient{{To: address.Address(owner.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(90000000))}}
+		registration := crown.NFTProtocolRegistration{
+			ID:                  "doc",
+			Name:                "Documents",
+			Owner:               address.Address(owner.EncodeAddress()),
+			RegSign:             crown.NFTRegSignCreator,
+			MetadataMimeType:    "application/json",
+			IsTokenTransferable: true,
+			MaxMetadataSize:     128,
+		}
+		signDigest := func(digest pack.Bytes32) pack.Bytes65 {
+			compact, err := btcec.SignCompact(btcec.S256(), privKey, digest[:], true)
+			Expect(err).ToNot(HaveOccurred())
+			rsv := pack.Bytes65{}
+			copy(rsv[:64], compact[1:])
+			rsv[64] = compact[0] - 27 - 4
+			return rsv
+		}
+
+		It("should sign the payload before the inputs", func() {
+			tx, err := crown.NewTxBuilder(params).BuildNFTProtocolRegistrationTx(inputs, recipients, registration)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = tx.Sighashes()
+			Expect(err).To(HaveOccurred())
+
+			payloadSighash, err := tx.PayloadSighash()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.SignPayload(signDigest(payloadSighash))).To(Succeed())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes).To(HaveLen(1))
+			Expect(tx.Sign([]pack.Bytes65{signDigest(sighashes[0])}, pack.NewBytes(privKey.PubKey().SerializeCompressed()))).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			hash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(hash)).To(Equal(chainhash.DoubleHashB(serial)))
+
+			// Version 3, with the type in the upper 16 bits.
+			Expect([]byte(serial[:4])).To(Equal([]byte{0x03, 0x00, 0x08, 0x00}))
+
+			// The payload starts with its version and the packed protocol id
+			// ('d', 'o', and 'c' are 9, 20, and 8 in the 5-bit charset).
+			msgTx := wire.NewMsgTx(0)
+			Expect(msgTx.Deserialize(bytes.NewReader(serial))).To(Succeed())
+			buf := new(bytes.Buffer)
+			Expect(msgTx.Serialize(buf)).To(Succeed())
+			extraPayload, err := wire.ReadVarBytes(bytes.NewReader(serial[buf.Len():]), 0, 1000, "payload")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extraPayload[:2]).To(Equal([]byte{0x01, 0x00}))
+			id := uint64(9)<<59 | uint64(20)<<54 | uint64(8)<<49
+			Expect(binary.LittleEndian.Uint64(extraPayload[2:10])).To(Equal(id))
+
+			// The payload ends with a compact signature from the owner.
+			compact := extraPayload[len(extraPayload)-65:]
+			Expect(extraPayload[len(extraPayload)-66]).To(Equal(byte(65)))
+			pubKey, compressed, err := btcec.RecoverCompact(btcec.S256(), compact, payloadSighash[:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compressed).To(BeTrue())
+			Expect(pubKey.IsEqual(privKey.PubKey())).To(BeTrue())
+			Expect(extraPayload[len(extraPayload)-66-20 : len(extraPayload)-66]).To(Equal(owner.ScriptAddress()))
+		})
+
+		It("should build token registrations", func() {
+			tx, err := crown.NewTxBuilder(params).BuildNFTIssuanceTx(inputs, recipients, crown.NFTIssuance{
+				ProtocolID: "doc",
+				ID:         newHash(tokenID),
+				Owner:      address.Address(owner.EncodeAddress()),
+				Metadata:   "https://crowncoin.org/doc/1.json",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			payloadSighash, err := tx.PayloadSighash()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.SignPayload(signDigest(payloadSighash))).To(Succeed())
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(serial[:4])).To(Equal([]byte{0x03, 0x00, 0x07, 0x00}))
+
+			// The identifier is serialized as a uint256, which is the reverse
+			// of its hex encoding in the RPC interface.
+			msgTx := wire.NewMsgTx(0)
+			Expect(msgTx.Deserialize(bytes.NewReader(serial))).To(Succeed())
+			buf := new(bytes.Buffer)
+			Expect(msgTx.Serialize(buf)).To(Succeed())
+			extraPayload, err := wire.ReadVarBytes(bytes.NewReader(serial[buf.Len():]), 0, 1000, "payload")
+			Expect(err).ToNot(HaveOccurred())
+			id, err := hex.DecodeString(tokenID)
+			Expect(err).ToNot(HaveOccurred())
+			for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+				id[i], id[j] = id[j], id[i]
+			}
+			Expect(extraPayload[10:42]).To(Equal(id))
+		})
+
+		It("should return an error for an invalid registration", func() {
+			txBuilder := crown.NewTxBuilder(params)
+			invalid := registration
+			invalid.Owner = address.Address("not an address")
+			_, err := txBuilder.BuildNFTProtocolRegistrationTx(inputs, recipients, invalid)
+			Expect(err).To(HaveOccurred())
+			invalid = registration
+			invalid.MaxMetadataSize = 256
+			_, err = txBuilder.BuildNFTProtocolRegistrationTx(inputs, recipients, invalid)
+			Expect(err).To(HaveOccurred())
+			_, err = txBuilder.BuildNFTIssuanceTx(inputs, recipients, crown.NFTIssuance{ProtocolID: "doc_6", Owner: registration.Owner})
+			Expect(err).To(HaveOccurred())
+
+			tx, err := txBuilder.BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = tx.(*crown.Tx).PayloadSighash()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,74 @@
+package crown_test
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/crown"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Params", func() {
+	DescribeTable("when looking up the params of a network",
+		func(network string, expected *crown.ChainParams) {
+			params, err := crown.ParamsForNetwork(network)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(params).To(Equal(expected))
+
+			// The genesis block must hash to the genesis hash.
+			Expect(params.GenesisBlock.BlockHash()).To(Equal(*params.GenesisHash))
+			Expect(params.Net).ToNot(BeZero())
+			Expect(params.PrivateKeyID).ToNot(BeZero())
+		},
+
+		Entry("should return the mainnet params", "mainnet", &crown.MainNetParams),
+		Entry("should return the testnet params", "testnet", &crown.TesnetParams),
+		Entry("should return the regtest params", "regtest", &crown.RegressionNetParams),
+	)
+
+	Context("when looking up an unknown network", func() {
+		It("should return an error", func() {
+			_, err := crown.ParamsForNetwork("devnet")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when decoding a regtest address with the mainnet params", func() {
+		It("should return an error", func() {
+			addr, err := crown.NewAddressPubKeyHash(bytes.Repeat([]byte{0xab}, 20), &crown.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = crown.NewAddressDecoder(&crown.MainNetParams).DecodeAddress(address.Address(addr.EncodeAddress()))
+			Expect(err).To(HaveOccurred())
+			_, err = crown.NewAddressDecoder(&crown.RegressionNetParams).DecodeAddress(address.Address(addr.EncodeAddress()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when encoding and decoding a private key", func() {
+		It("should round-trip for the same network", func() {
+			privKey, err := btcec.NewPrivateKey(btcec.S256())
+			Expect(err).ToNot(HaveOccurred())
+			wif, err := crown.EncodeWIF(privKey, true, &crown.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(wif[0]).To(Equal(byte('c')))
+
+			decoded, err := crown.DecodeWIF(wif, &crown.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.PrivKey.Serialize()).To(Equal(privKey.Serialize()))
+			Expect(decoded.CompressPubKey).To(BeTrue())
+		})
+
+		It("should return an error for a different network", func() {
+			privKey, err := btcec.NewPrivateKey(btcec.S256())
+			Expect(err).ToNot(HaveOccurred())
+			wif, err := crown.EncodeWIF(privKey, true, &crown.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = crown.DecodeWIF(wif, &crown.RegressionNetParams)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,111 @@
+package crown
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/pack"
+)
+
+// SpecialTxVersion is the version of Crown transactions that carry a typed
+// payload (for example, NFT registrations). The type of the payload is encoded
+// in the upper 16 bits of the transaction version.
+const SpecialTxVersion = 3
+
+// A SpecialTxType identifies the kind of payload carried by a special
+// transaction.
+type SpecialTxType uint16
+
+// Enumerate the special transaction types that can be built.
+const (
+	SpecialTxTypeNFTRegister         = SpecialTxType(7)
+	SpecialTxTypeNFTProtocolRegister = SpecialTxType(8)
+)
+
+// newSpecialTx turns a transaction built by the TxBuilder into a special
+// transaction that carries the given payload. The payload must not include its
+// signature.
+func newSpecialTx(tx *Tx, txType SpecialTxType, payload []byte) *Tx {
+	tx.msgTx.Version = int32(SpecialTxVersion | uint32(txType)<<16)
+	tx.txType = txType
+	tx.payload = payload
+	return tx
+}
+
+// PayloadSighash returns the digest that must be signed by the owner of the
+// payload of a special transaction (for example, the owner of the NFT protocol
+// being registered). The payload must be signed using SignPayload before the
+// inputs of the transaction can be signed, because the signature of the
+// payload is committed to by the sighashes of the inputs.
+func (tx *Tx) PayloadSighash() (pack.Bytes32, error) {
+	if tx.txType == 0 {
+		return pack.Bytes32{}, fmt.Errorf("expected special tx")
+	}
+	return pack.Bytes32(chainhash.DoubleHashH(tx.payload)), nil
+}
+
+// SignPayload of a special transaction, using a signature of the digest
+// returned by PayloadSighash. The last byte of the signature is the recovery
+// id, and the signature must be produced by a compressed public key.
+func (tx *Tx) SignPayload(signature pack.Bytes65) error {
+	if tx.txType == 0 {
+		return fmt.Errorf("expected special tx")
+	}
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if signature[64] > 3 {
+		return fmt.Errorf("bad recovery id: %v", signature[64])
+	}
+	// Payloads use compact signatures, which start with the recovery id.
+	compact := make([]byte, 65)
+	compact[0] = 27 + 4 + signature[64]
+	copy(compact[1:], signature[:64])
+	tx.payloadSig = compact
+	return nil
+}
+
+// extraPayload returns the payload of a special transaction, including its
+// signature.
+func (tx *Tx) extraPayload() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Write(tx.payload)
+	if err := wire.WriteVarBytes(buf, 0, tx.payloadSig); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// specialSighash returns the legacy SIGHASH_ALL digest for an input of a
+// special transaction. It is the same as the digest returned by
+// txscript.CalcSignatureHash, except that the extra payload is serialized
+// after the lock time.
+func (tx *Tx) specialSighash(script []byte, idx int) ([]byte, error) {
+	extraPayload, err := tx.extraPayload()
+	if err != nil {
+		return nil, err
+	}
+	txCopy := tx.msgTx.Copy()
+	for i := range txCopy.TxIn {
+		if i == idx {
+			txCopy.TxIn[i].SignatureScript = script
+		} else {
+			txCopy.TxIn[i].SignatureScript = nil
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := txCopy.SerializeNoWitness(buf); err != nil {
+		return nil, err
+	}
+	if err := wire.WriteVarBytes(buf, 0, extraPayload); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, uint32(txscript.SigHashAll)); err != nil {
+		return nil, err
+	}
+	return chainhash.DoubleHashB(buf.Bytes()), nil
+}
@@ -13,15 +13,14 @@ import
 	"github.com/renproject/pack"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/base58"
 )
 
 type (
-	Client        = bitcoin.Client
 	ClientOptions = bitcoin.ClientOptions
 )
 
 var (
-	NewClient            = bitcoin.NewClient
 	DefaultClientOptions = bitcoin.DefaultClientOptions
 )
 
@@ -40,13 +39,32 @@ type Tx struct {
 	params *ChainParams
 	expiryHeight uint32
 	signed bool
+
+	// The type and payload of special transactions. The payload does not
+	// include its signature.
+	txType     SpecialTxType
+	payload    []byte
+	payloadSig []byte
 }
 
 func (tx *Tx) Hash() (pack.Bytes, error) {
+	if tx.txType != 0 {
+		// The hash of a special transaction commits to its payload.
+		serial, err := tx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		return pack.NewBytes(chainhash.DoubleHashB(serial)), nil
+	}
 	txhash := tx.msgTx.TxHash()
 	return pack.NewBytes(txhash[:]), nil
 }
 
+// Params returns the network for which the transaction was built.
+func (tx *Tx) Params() *ChainParams {
+	return tx.params
+}
+
 func (tx *Tx) Inputs() ([]utxo.Input, error) {
 	return tx.inputs, nil
 }
@@ -75,6 +93,9 @@ func (tx *Tx) Outputs() ([]utxo.Output, error) {
 // can be submitted by the client.
 
 func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
+	if tx.txType != 0 && tx.payloadSig == nil {
+		return nil, fmt.Errorf("expected signed payload")
+	}
 	sighashes := make([]pack.Bytes32, len(tx.inputs))
   
 	for i, txin := range tx.inputs {
@@ -87,7 +108,13 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
   
 	  var hash []byte
 	  var err error
-	  if sigScript == nil {
+	  if tx.txType != 0 {
+		script := pubKeyScript
+		if sigScript != nil {
+			script = sigScript
+		}
+		hash, err = tx.specialSighash(script, i)
+	  } else if sigScript == nil {
 		hash, err = txscript.CalcSignatureHash(pubKeyScript, txscript.SigHashAll, tx.msgTx, i)
 	  } else {
 		hash, err = txscript.CalcSignatureHash(sigScript, txscript.SigHashAll, tx.msgTx, i)
@@ -105,7 +132,8 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 	return sighashes, nil
 }
 
-// Signs the built transaction.
+// Signs the built transaction. Inputs that have already been signed using
+// SignMultisig are skipped, and their signatures are ignored.
 
 func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	if tx.signed {
@@ -118,6 +146,11 @@ func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	for i, rsv := range signatures {
 		var err error
 
+		// Skip inputs that have been signed using SignMultisig.
+		if len(tx.msgTx.TxIn[i].SignatureScript) > 0 {
+			continue
+		}
+
 		// Decode the signature and the pubkey script.
 		r := new(big.Int).SetBytes(rsv[:32])
 		s := new(big.Int).SetBytes(rsv[32:64])
@@ -150,6 +183,15 @@ func (tx *Tx) Serialize() (pack.Bytes, error) {
 	if err := tx.msgTx.Serialize(buf); err != nil {
 		return pack.Bytes{}, err
 	}
+	if tx.txType != 0 {
+		extraPayload, err := tx.extraPayload()
+		if err != nil {
+			return pack.Bytes{}, err
+		}
+		if err := wire.WriteVarBytes(buf, 0, extraPayload); err != nil {
+			return pack.Bytes{}, err
+		}
+	}
 	return pack.NewBytes(buf.Bytes()), nil
 }
 
@@ -165,11 +207,13 @@ func NewTxBuilder(params *ChainParams) TxBuilder {
 }
 
 // BuildTx returns a Crown transaction that consumes funds from the given
-// inputs, and sends them to the given recipients. The fee is paid to
-// the Crown network is fixed to 0.01 CRW since the network doesn't accepts high fees.
-// Outputs produced for recipients will use P2PKH, P2SH
-// scripts as the pubkey script, based on the format of the recipient address.
-
+// inputs, and sends them to the given recipients. The difference in the sum
+// value of the inputs and the sum value of the recipients is paid as a fee to
+// the Crown network. Use Fee (with the SATs-per-byte returned by a
+// NodeGasEstimator) to compute the fee that the transaction must pay. Outputs
+// produced for recipients will use P2PKH, P2SH scripts as the pubkey script,
+// based on the format of the recipient address. Recipients must have addresses
+// for the network of the transaction builder.
 func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error){
 	msgTx := wire.NewMsgTx(Version)
 	// Inputs
@@ -182,6 +226,9 @@ func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipi
 
 	// Outputs
 	for _, recipient := range recipients {
+		if err := verifyRawAddress(base58.Decode(string(recipient.To)), txBuilder.params); err != nil {
+			return nil, fmt.Errorf("bad recipient %v: %v", recipient.To, err)
+		}
 		addr, err := DecodeAddress(string(recipient.To))
 		if err != nil {
 			return nil, err
@@ -196,5 +243,5 @@ func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipi
 		}
 		msgTx.AddTxOut(wire.NewTxOut(value, script))
 	}
-	return &Tx{inputs: inputs, recipients: recipients, msgTx: msgTx, signed: false}, nil
+	return &Tx{inputs: inputs, recipients: recipients, msgTx: msgTx, params: txBuilder.params, signed: false}, nil
 }
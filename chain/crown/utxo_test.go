@@ -1 +1,54 @@
 package crown_test
+
+import (
+	"bytes"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/crown"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TxBuilder", func() {
+	hash := bytes.Repeat([]byte{0xab}, 20)
+	inputs := []utxo.Input{
+		{
+			Output: utxo.Output{
+				Outpoint: utxo.Outpoint{Hash: pack.Bytes(bytes.Repeat([]byte{0x01}, 32)), Index: pack.NewU32(0)},
+				Value:    pack.NewU256FromU64(pack.NewU64(100000)),
+			},
+		},
+	}
+	newRecipient := func(params *crown.ChainParams) utxo.Recipient {
+		addr, err := crown.NewAddressPubKeyHash(hash, params)
+		Expect(err).ToNot(HaveOccurred())
+		return utxo.Recipient{
+			To:    address.Address(addr.EncodeAddress()),
+			Value: pack.NewU256FromU64(pack.NewU64(90000)),
+		}
+	}
+
+	Context("when the recipient is on the same network", func() {
+		It("should build a transaction for the network", func() {
+			txBuilder := crown.NewTxBuilder(&crown.TesnetParams)
+			tx, err := txBuilder.BuildTx(inputs, []utxo.Recipient{newRecipient(&crown.TesnetParams)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.(*crown.Tx).Params()).To(Equal(&crown.TesnetParams))
+		})
+	})
+
+	Context("when the recipient is on a different network", func() {
+		It("should return an error", func() {
+			txBuilder := crown.NewTxBuilder(&crown.MainNetParams)
+			_, err := txBuilder.BuildTx(inputs, []utxo.Recipient{newRecipient(&crown.TesnetParams)})
+			Expect(err).To(HaveOccurred())
+
+			txBuilder = crown.NewTxBuilder(&crown.TesnetParams)
+			_, err = txBuilder.BuildTx(inputs, []utxo.Recipient{newRecipient(&crown.MainNetParams)})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -3,3 +3,5 @@ package digibyte
 import "github.com/renproject/multichain/chain/bitcoin"
 
 type GasEstimator = bitcoin.GasEstimator
+
+type NodeGasEstimator = bitcoin.NodeGasEstimator
@@ -1 +1,430 @@
 package ethereum
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+// DefaultGasLimit is the gas required by a transfer of ether between two
+// external accounts, with no payload.
+const DefaultGasLimit = 21000
+
+// The TxBuilder is an implementation of an account-compatible transaction
+// builder for Ethereum. It builds EIP-155 transactions that are replay
+// protected for a specific chain ID, which means it can also be used for
+// Ethereum-compatible chains (e.g. BinanceSmartChain, Celo, and Fantom). It can
+// also build EIP-1559 dynamic fee transactions for chains that have a fee
+// market.
+type TxBuilder struct {
+	chainID  pack.U256
+	gasLimit pack.U64
+	gasPrice pack.U256
+
+	dynamicFee bool
+	gasFeeCap  pack.U256
+	gasTipCap  pack.U256
+}
+
+// NewTxBuilder returns a transaction builder that builds EIP-155 transactions
+// for the given chain ID. All transactions will use the given gas limit and gas
+// price (in wei-per-gas).
+func NewTxBuilder(chainID pack.U256, gasLimit pack.U64, gasPrice pack.U256) TxBuilder {
+	return TxBuilder{
+		chainID:  chainID,
+		gasLimit: gasLimit,
+		gasPrice: gasPrice,
+	}
+}
+
+// NewDynamicFeeTxBuilder returns a transaction builder that builds EIP-1559
+// (type 2) transactions for the given chain ID. All transactions will use the
+// given gas limit, fee cap, and tip cap (in wei-per-gas). The fee cap and tip
+// cap are usually the result of a gas.DynamicFeeEstimator.
+func NewDynamicFeeTxBuilder(chainID pack.U256, gasLimit pack.U64, gasFeeCap, gasTipCap pack.U256) TxBuilder {
+	return TxBuilder{
+		chainID:    chainID,
+		gasLimit:   gasLimit,
+		dynamicFee: true,
+		gasFeeCap:  gasFeeCap,
+		gasTipCap:  gasTipCap,
+	}
+}
+
+// BuildTx returns an unsigned Ethereum transaction that sends value from one
+// address to another, and passes the payload as calldata. If the recipient is
+// empty, then the transaction is a contract creation and the payload is the
+// contract bytecode.
+func (txBuilder TxBuilder) BuildTx(from, to address.Address, value, nonce pack.U256, payload pack.Bytes) (account.Tx, error) {
+	fromAddr, err := NewAddressFromHex(string(from))
+	if err != nil {
+		return nil, fmt.Errorf("bad from address: %v", err)
+	}
+	if !nonce.Int().IsUint64() {
+		return nil, fmt.Errorf("expected nonce < 2^64, got nonce %v", nonce)
+	}
+	var toAddr *common.Address
+	if to != "" {
+		addr, err := NewAddressFromHex(string(to))
+		if err != nil {
+			return nil, fmt.Errorf("bad to address: %v", err)
+		}
+		toAddr = (*common.Address)(&addr)
+	}
+
+	if txBuilder.dynamicFee {
+		if txBuilder.gasFeeCap.Int().Cmp(txBuilder.gasTipCap.Int()) < 0 {
+			return nil, fmt.Errorf("expected fee cap >= tip cap, got fee cap %v and tip cap %v", txBuilder.gasFeeCap, txBuilder.gasTipCap)
+		}
+		return &DynamicFeeTx{
+			data: dynamicFeeTxData{
+				ChainID:    txBuilder.chainID.Int(),
+				Nonce:      nonce.Int().Uint64(),
+				GasTipCap:  txBuilder.gasTipCap.Int(),
+				GasFeeCap:  txBuilder.gasFeeCap.Int(),
+				Gas:        txBuilder.gasLimit.Uint64(),
+				To:         toAddr,
+				Value:      value.Int(),
+				Data:       payload,
+				AccessList: []AccessTuple{},
+				V:          new(big.Int),
+				R:          new(big.Int),
+				S:          new(big.Int),
+			},
+			from:   fromAddr,
+			signed: false,
+		}, nil
+	}
+
+	var tx *types.Transaction
+	if toAddr == nil {
+		tx = types.NewContractCreation(nonce.Int().Uint64(), value.Int(), txBuilder.gasLimit.Uint64(), txBuilder.gasPrice.Int(), payload)
+	} else {
+		tx = types.NewTransaction(nonce.Int().Uint64(), *toAddr, value.Int(), txBuilder.gasLimit.Uint64(), txBuilder.gasPrice.Int(), payload)
+	}
+	return &Tx{
+		tx:     tx,
+		signer: types.NewEIP155Signer(txBuilder.chainID.Int()),
+		from:   fromAddr,
+		signed: false,
+	}, nil
+}
+
+// Tx represents an Ethereum transaction that implements the Account API.
+type Tx struct {
+	tx     *types.Transaction
+	signer types.Signer
+	from   Address
+
+	signed bool
+}
+
+// Hash returns the Keccak256 hash of the RLP encoded transaction. The hash
+// changes once the transaction is signed.
+func (tx *Tx) Hash() pack.Bytes {
+	return pack.NewBytes(tx.tx.Hash().Bytes())
+}
+
+// From returns the address from which value is being sent.
+func (tx *Tx) From() address.Address {
+	return address.Address(tx.from.String())
+}
+
+// To returns the address to which value is being sent. An empty address is
+// returned for contract creations.
+func (tx *Tx) To() address.Address {
+	to := tx.tx.To()
+	if to == nil {
+		return address.Address("")
+	}
+	return address.Address(Address(*to).String())
+}
+
+// Value being sent from one address to another, in wei.
+func (tx *Tx) Value() pack.U256 {
+	return pack.NewU256FromInt(tx.tx.Value())
+}
+
+// Nonce of the sender.
+func (tx *Tx) Nonce() pack.U256 {
+	return pack.NewU256FromU64(pack.NewU64(tx.tx.Nonce()))
+}
+
+// Payload returns the calldata associated with the transaction.
+func (tx *Tx) Payload() contract.CallData {
+	return contract.CallData(pack.NewBytes(tx.tx.Data()))
+}
+
+// Sighashes returns the digest that must be signed before the transaction can
+// be submitted by the client.
+func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
+	sighash := tx.signer.Hash(tx.tx)
+	return []pack.Bytes32{pack.NewBytes32(sighash)}, nil
+}
+
+// Sign the transaction by injecting the signature for the sighash. The
+// signature must be in the 65 byte [R || S || V] format, where V is the
+// recovery ID (0 or 1, although 27 and 28 are also accepted). The public key is
+// not needed, because Ethereum recovers it from the signature. Instead, the
+// recovered sender is checked against the address from which the transaction
+// was built.
+func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if len(signatures) != 1 {
+		return fmt.Errorf("expected 1 signature, got %v signatures", len(signatures))
+	}
+
+	sig, err := normaliseSignature(signatures[0])
+	if err != nil {
+		return err
+	}
+	signedTx, err := tx.tx.WithSignature(tx.signer, sig[:])
+	if err != nil {
+		return err
+	}
+	sender, err := types.Sender(tx.signer, signedTx)
+	if err != nil {
+		return fmt.Errorf("bad signature: %v", err)
+	}
+	if Address(sender) != tx.from {
+		return fmt.Errorf("bad signature: expected sender %v, got sender %v", tx.from, Address(sender))
+	}
+	tx.tx = signedTx
+	tx.signed = true
+	return nil
+}
+
+// Serialize the transaction into its RLP encoding. This is the format in which
+// the transaction will be submitted by the client.
+func (tx *Tx) Serialize() (pack.Bytes, error) {
+	serial, err := rlp.EncodeToBytes(tx.tx)
+	if err != nil {
+		return pack.Bytes{}, err
+	}
+	return pack.NewBytes(serial), nil
+}
+
+// ChainID for which the transaction is replay protected.
+func (tx *Tx) ChainID() pack.U256 {
+	chainID := tx.tx.ChainId()
+	if chainID == nil {
+		return pack.NewU256FromInt(big.NewInt(0))
+	}
+	return pack.NewU256FromInt(chainID)
+}
+
+// DynamicFeeTxType is the EIP-2718 type of EIP-1559 transactions.
+const DynamicFeeTxType = 0x02
+
+// An AccessTuple is an entry in the access list of an EIP-2930 (or EIP-1559)
+// transaction. It declares the storage keys, of an address, that will be
+// accessed by the transaction.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+type dynamicFeeTxData struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"`
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple
+	V, R, S    *big.Int
+}
+
+// DynamicFeeTx represents an EIP-1559 (type 2) Ethereum transaction that
+// implements the Account API. Instead of a gas price, it specifies a fee cap
+// and a tip cap, and the sender only pays the base fee of the block in which
+// the transaction is included (plus the tip).
+type DynamicFeeTx struct {
+	data dynamicFeeTxData
+	from Address
+
+	signed bool
+}
+
+// Hash returns the Keccak256 hash of the serialized transaction. The hash
+// changes once the transaction is signed.
+func (tx *DynamicFeeTx) Hash() pack.Bytes {
+	serial, err := tx.Serialize()
+	if err != nil {
+		return pack.Bytes{}
+	}
+	return pack.NewBytes(crypto.Keccak256(serial))
+}
+
+// From returns the address from which value is being sent.
+func (tx *DynamicFeeTx) From() address.Address {
+	return address.Address(tx.from.String())
+}
+
+// To returns the address to which value is being sent. An empty address is
+// returned for contract creations.
+func (tx *DynamicFeeTx) To() address.Address {
+	if tx.data.To == nil {
+		return address.Address("")
+	}
+	return address.Address(Address(*tx.data.To).String())
+}
+
+// Value being sent from one address to another, in wei.
+func (tx *DynamicFeeTx) Value() pack.U256 {
+	return pack.NewU256FromInt(tx.data.Value)
+}
+
+// Nonce of the sender.
+func (tx *DynamicFeeTx) Nonce() pack.U256 {
+	return pack.NewU256FromU64(pack.NewU64(tx.data.Nonce))
+}
+
+// Payload returns the calldata associated with the transaction.
+func (tx *DynamicFeeTx) Payload() contract.CallData {
+	return contract.CallData(pack.NewBytes(tx.data.Data))
+}
+
+// ChainID for which the transaction is replay protected.
+func (tx *DynamicFeeTx) ChainID() pack.U256 {
+	return pack.NewU256FromInt(tx.data.ChainID)
+}
+
+// GasFeeCap is the maximum total price-per-gas that the sender will pay.
+func (tx *DynamicFeeTx) GasFeeCap() pack.U256 {
+	return pack.NewU256FromInt(tx.data.GasFeeCap)
+}
+
+// GasTipCap is the maximum price-per-gas that the sender will pay to the block
+// producer.
+func (tx *DynamicFeeTx) GasTipCap() pack.U256 {
+	return pack.NewU256FromInt(tx.data.GasTipCap)
+}
+
+// Sighashes returns the digest that must be signed before the transaction can
+// be submitted by the client. This is the Keccak256 hash of the transaction
+// type concatenated with the RLP encoding of the unsigned transaction fields.
+func (tx *DynamicFeeTx) Sighashes() ([]pack.Bytes32, error) {
+	sighash, err := tx.sighash()
+	if err != nil {
+		return nil, err
+	}
+	return []pack.Bytes32{sighash}, nil
+}
+
+// Sign the transaction by injecting the signature for the sighash. The
+// signature must be in the 65 byte [R || S || V] format, where V is the
+// recovery ID (0 or 1, although 27 and 28 are also accepted). The recovered
+// sender is checked against the address from which the transaction was built.
+func (tx *DynamicFeeTx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if len(signatures) != 1 {
+		return fmt.Errorf("expected 1 signature, got %v signatures", len(signatures))
+	}
+
+	sig, err := normaliseSignature(signatures[0])
+	if err != nil {
+		return err
+	}
+	sighash, err := tx.sighash()
+	if err != nil {
+		return err
+	}
+	sender, err := recoverSender(sighash, sig)
+	if err != nil {
+		return fmt.Errorf("bad signature: %v", err)
+	}
+	if sender != tx.from {
+		return fmt.Errorf("bad signature: expected sender %v, got sender %v", tx.from, sender)
+	}
+
+	tx.data.R = new(big.Int).SetBytes(sig[:32])
+	tx.data.S = new(big.Int).SetBytes(sig[32:64])
+	tx.data.V = new(big.Int).SetUint64(uint64(sig[64]))
+	tx.signed = true
+	return nil
+}
+
+// Serialize the transaction into its EIP-2718 encoding: the transaction type
+// followed by the RLP encoding of the transaction fields. This is the format in
+// which the transaction will be submitted by the client.
+func (tx *DynamicFeeTx) Serialize() (pack.Bytes, error) {
+	serial, err := rlp.EncodeToBytes(&tx.data)
+	if err != nil {
+		return pack.Bytes{}, err
+	}
+	return pack.NewBytes(append([]byte{DynamicFeeTxType}, serial...)), nil
+}
+
+func (tx *DynamicFeeTx) sighash() (pack.Bytes32, error) {
+	serial, err := rlp.EncodeToBytes([]interface{}{
+		tx.data.ChainID,
+		tx.data.Nonce,
+		tx.data.GasTipCap,
+		tx.data.GasFeeCap,
+		tx.data.Gas,
+		tx.data.To,
+		tx.data.Value,
+		tx.data.Data,
+		tx.data.AccessList,
+	})
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	sighash := [32]byte{}
+	copy(sighash[:], crypto.Keccak256([]byte{DynamicFeeTxType}, serial))
+	return pack.NewBytes32(sighash), nil
+}
+
+// sender recovers the address that signed the transaction.
+func (tx *DynamicFeeTx) sender() (Address, error) {
+	if tx.data.V.Cmp(big.NewInt(1)) > 0 || tx.data.R.BitLen() > 256 || tx.data.S.BitLen() > 256 {
+		return Address{}, fmt.Errorf("bad signature values")
+	}
+	sighash, err := tx.sighash()
+	if err != nil {
+		return Address{}, err
+	}
+	sig := pack.Bytes65{}
+	math.ReadBits(tx.data.R, sig[:32])
+	math.ReadBits(tx.data.S, sig[32:64])
+	sig[64] = byte(tx.data.V.Uint64())
+	return recoverSender(sighash, sig)
+}
+
+// recoverSender returns the address of the public key that produced the
+// signature over the sighash.
+func recoverSender(sighash pack.Bytes32, sig pack.Bytes65) (Address, error) {
+	pubKey, err := crypto.SigToPub(sighash[:], sig[:])
+	if err != nil {
+		return Address{}, err
+	}
+	return Address(crypto.PubkeyToAddress(*pubKey)), nil
+}
+
+// normaliseSignature so that the recovery ID is 0 or 1, as expected by the
+// Ethereum signature encodings.
+func normaliseSignature(sig pack.Bytes65) (pack.Bytes65, error) {
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	if sig[64] > 1 {
+		return sig, fmt.Errorf("expected recovery id 0 or 1, got recovery id %v", sig[64])
+	}
+	return sig, nil
+}
@@ -1 +1,246 @@
 package ethereum_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TxBuilder", func() {
+	chainID := pack.NewU256FromU64(pack.NewU64(1337))
+	gasPrice := pack.NewU256FromU64(pack.NewU64(1e9))
+	txBuilder := ethereum.NewTxBuilder(chainID, pack.NewU64(ethereum.DefaultGasLimit), gasPrice)
+
+	privKey := id.NewPrivKey()
+	from := address.Address(ethereum.Address(crypto.PubkeyToAddress(privKey.PublicKey)).String())
+	to := address.Address("797522Fb74d42bB9fbF6b76dEa24D01A538d5D66")
+	value := pack.NewU256FromU64(pack.NewU64(1000))
+	nonce := pack.NewU256FromU64(pack.NewU64(7))
+
+	sign := func(sighash pack.Bytes32) pack.Bytes65 {
+		hash := id.Hash(sighash)
+		signature, err := privKey.Sign(&hash)
+		Expect(err).ToNot(HaveOccurred())
+		return pack.NewBytes65(signature)
+	}
+
+	Context("when building and signing a transaction", func() {
+		It("should produce the same transaction as go-ethereum", func() {
+			tx, err := txBuilder.BuildTx(from, to, value, nonce, pack.Bytes{0xca, 0xfe})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.From()).To(Equal(from))
+			Expect(tx.Value()).To(Equal(value))
+			Expect(tx.Nonce()).To(Equal(nonce))
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes).To(HaveLen(1))
+			Expect(tx.Sign([]pack.Bytes65{sign(sighashes[0])}, nil)).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedTx, err := types.SignTx(
+				types.NewTransaction(7, common.HexToAddress(string(to)), big.NewInt(1000), ethereum.DefaultGasLimit, big.NewInt(1e9), []byte{0xca, 0xfe}),
+				types.NewEIP155Signer(big.NewInt(1337)),
+				(*ecdsa.PrivateKey)(privKey),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			expectedSerial, err := rlp.EncodeToBytes(expectedTx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(serial)).To(Equal(expectedSerial))
+			Expect([]byte(tx.Hash())).To(Equal(expectedTx.Hash().Bytes()))
+		})
+	})
+
+	Context("when the signature uses a recovery id of 27 or 28", func() {
+		It("should normalise the recovery id", func() {
+			tx, err := txBuilder.BuildTx(from, to, value, nonce, nil)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+
+			signature := sign(sighashes[0])
+			signature[64] += 27
+			Expect(tx.Sign([]pack.Bytes65{signature}, nil)).To(Succeed())
+		})
+	})
+
+	Context("when the signature is from a different sender", func() {
+		It("should return an error", func() {
+			tx, err := txBuilder.BuildTx(to, to, value, nonce, nil)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.Sign([]pack.Bytes65{sign(sighashes[0])}, nil)).ToNot(Succeed())
+		})
+	})
+
+	Context("when signing twice", func() {
+		It("should return an error", func() {
+			tx, err := txBuilder.BuildTx(from, to, value, nonce, nil)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.Sign([]pack.Bytes65{sign(sighashes[0])}, nil)).To(Succeed())
+			Expect(tx.Sign([]pack.Bytes65{sign(sighashes[0])}, nil)).ToNot(Succeed())
+		})
+	})
+
+	Context("when the nonce is too large", func() {
+		It("should return an error", func() {
+			nonce := pack.NewU256FromInt(new(big.Int).Lsh(big.NewInt(1), 64))
+			_, err := txBuilder.BuildTx(from, to, value, nonce, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building and signing a dynamic fee transaction", func() {
+		It("should produce a type 2 transaction that round-trips through the client", func() {
+			gasFeeCap := pack.NewU256FromU64(pack.NewU64(2e9))
+			gasTipCap := pack.NewU256FromU64(pack.NewU64(1e9))
+			txBuilder := ethereum.NewDynamicFeeTxBuilder(chainID, pack.NewU64(ethereum.DefaultGasLimit), gasFeeCap, gasTipCap)
+
+			tx, err := txBuilder.BuildTx(from, to, value, nonce, pack.Bytes{0xca, 0xfe})
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes).To(HaveLen(1))
+			signature := sign(sighashes[0])
+			Expect(tx.Sign([]pack.Bytes65{signature}, nil)).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serial[0]).To(Equal(byte(ethereum.DynamicFeeTxType)))
+			Expect([]byte(tx.Hash())).To(Equal(crypto.Keccak256(serial)))
+
+			fields := []rlp.RawValue{}
+			Expect(rlp.DecodeBytes(serial[1:], &fields)).To(Succeed())
+			Expect(fields).To(HaveLen(12))
+
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{
+						"type":                 "0x2",
+						"chainId":              "0x539",
+						"nonce":                "0x7",
+						"maxPriorityFeePerGas": hexutil.Uint64(1e9),
+						"maxFeePerGas":         hexutil.Uint64(2e9),
+						"gas":                  hexutil.Uint64(ethereum.DefaultGasLimit),
+						"to":                   common.HexToAddress(string(to)),
+						"value":                "0x3e8",
+						"input":                "0xcafe",
+						"accessList":           []interface{}{},
+						"v":                    hexutil.Uint64(signature[64]),
+						"r":                    (*hexutil.Big)(new(big.Int).SetBytes(signature[:32])),
+						"s":                    (*hexutil.Big)(new(big.Int).SetBytes(signature[32:64])),
+						"blockNumber":          nil,
+					}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			fetchedTx, _, err := client.Tx(context.Background(), tx.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetchedTx.Hash()).To(Equal(tx.Hash()))
+			Expect(fetchedTx.From()).To(Equal(from))
+		})
+	})
+
+	Context("when encoding a dynamic fee transaction", func() {
+		It("should match the EIP-1559 encoding", func() {
+			// The fields are small enough that the RLP encoding can be written
+			// by hand, following the layout in EIP-1559:
+			//
+			//   0x02 || rlp([chain_id, nonce, max_priority_fee_per_gas,
+			//     max_fee_per_gas, gas_limit, destination, amount, data,
+			//     access_list, signature_y_parity, signature_r, signature_s])
+			toAddr := common.HexToAddress("0x797522Fb74d42bB9fbF6b76dEa24D01A538d5D66")
+			fields := []byte{
+				0x01,             // chain_id = 1
+				0x80,             // nonce = 0
+				0x01,             // max_priority_fee_per_gas = 1
+				0x02,             // max_fee_per_gas = 2
+				0x82, 0x52, 0x08, // gas_limit = 21000
+				0x94, // destination (20 bytes)
+			}
+			fields = append(fields, toAddr.Bytes()...)
+			fields = append(fields,
+				0x80, // amount = 0
+				0x80, // data = empty
+				0xc0, // access_list = empty
+			)
+			Expect(fields).To(HaveLen(31))
+			preimage := append([]byte{0x02, 0xc0 + 31}, fields...)
+
+			key, err := crypto.HexToECDSA("4646464646464646464646464646464646464646464646464646464646464646")
+			Expect(err).ToNot(HaveOccurred())
+			keyFrom := address.Address(ethereum.Address(crypto.PubkeyToAddress(key.PublicKey)).String())
+			txBuilder := ethereum.NewDynamicFeeTxBuilder(
+				pack.NewU256FromU64(pack.NewU64(1)),
+				pack.NewU64(21000),
+				pack.NewU256FromU64(pack.NewU64(2)),
+				pack.NewU256FromU64(pack.NewU64(1)),
+			)
+			tx, err := txBuilder.BuildTx(keyFrom, address.Address(toAddr.Hex()), pack.NewU256FromU64(pack.NewU64(0)), pack.NewU256FromU64(pack.NewU64(0)), nil)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes[0][:]).To(Equal(crypto.Keccak256(preimage)))
+
+			signature, err := crypto.Sign(crypto.Keccak256(preimage), key)
+			Expect(err).ToNot(HaveOccurred())
+			// Leading zero bytes would be stripped from R and S by RLP, which
+			// would change the lengths below.
+			Expect(signature[0]).ToNot(BeZero())
+			Expect(signature[32]).ToNot(BeZero())
+			sig := pack.Bytes65{}
+			copy(sig[:], signature)
+			Expect(tx.Sign([]pack.Bytes65{sig}, nil)).To(Succeed())
+
+			// The signed fields are 31 bytes, the y parity is 1 byte, and R and
+			// S are 33 bytes each, for a list of 98 bytes.
+			expected := append([]byte{0x02, 0xf8, 98}, fields...)
+			if signature[64] == 0 {
+				expected = append(expected, 0x80)
+			} else {
+				expected = append(expected, 0x01)
+			}
+			expected = append(expected, 0xa0)
+			expected = append(expected, signature[:32]...)
+			expected = append(expected, 0xa0)
+			expected = append(expected, signature[32:64]...)
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(serial)).To(Equal(expected))
+			Expect([]byte(tx.Hash())).To(Equal(crypto.Keccak256(expected)))
+		})
+	})
+
+	Context("when the fee cap is less than the tip cap", func() {
+		It("should return an error", func() {
+			gasFeeCap := pack.NewU256FromU64(pack.NewU64(1e9))
+			gasTipCap := pack.NewU256FromU64(pack.NewU64(2e9))
+			txBuilder := ethereum.NewDynamicFeeTxBuilder(chainID, pack.NewU64(ethereum.DefaultGasLimit), gasFeeCap, gasTipCap)
+			_, err := txBuilder.BuildTx(from, to, value, nonce, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -4,66 +4,131 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/renproject/multichain/api/address"
 	"github.com/renproject/pack"
 	"github.com/renproject/surge"
 )
 
+// AddressEncodeDecoder implements the address.EncodeDecoder interface.
 type AddressEncodeDecoder struct {
 	AddressEncoder
 	AddressDecoder
 }
 
+// NewAddressEncodeDecoder returns an implementation of the address
+// EncodeDecoder interface that uses EIP-55 checksums.
+func NewAddressEncodeDecoder() address.EncodeDecoder {
+	return AddressEncodeDecoder{
+		AddressEncoder: NewAddressEncoder(),
+		AddressDecoder: NewAddressDecoder(),
+	}
+}
+
+// NewAddressEncodeDecoderWithChainID returns an implementation of the address
+// EncodeDecoder interface that uses EIP-1191 checksums for the given chain ID.
+// This should only be used for chains that have adopted EIP-1191 (for example,
+// RSK).
+func NewAddressEncodeDecoderWithChainID(chainID pack.U64) address.EncodeDecoder {
+	return AddressEncodeDecoder{
+		AddressEncoder: NewAddressEncoderWithChainID(chainID),
+		AddressDecoder: NewAddressDecoderWithChainID(chainID),
+	}
+}
+
+// AddressEncoder encodes raw addresses into human-readable addresses.
 type AddressEncoder interface {
 	EncodeAddress(address.RawAddress) (address.Address, error)
 }
 
-type addressEncoder struct{}
+type addressEncoder struct {
+	chainID *pack.U64
+}
 
-func NewAddressEncodeDecoder() address.EncodeDecoder {
-	return AddressEncodeDecoder{
-		AddressEncoder: NewAddressEncoder(),
-		AddressDecoder: NewAddressDecoder(),
+// NewAddressEncoder returns an AddressEncoder that encodes addresses as 0x
+// prefixed hex strings with an EIP-55 checksum.
+func NewAddressEncoder() AddressEncoder {
+	return addressEncoder{}
+}
+
+// NewAddressEncoderWithChainID returns an AddressEncoder that encodes
+// addresses as 0x prefixed hex strings with an EIP-1191 checksum for the given
+// chain ID.
+func NewAddressEncoderWithChainID(chainID pack.U64) AddressEncoder {
+	return addressEncoder{chainID: &chainID}
+}
+
+func (encoder addressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	if len(rawAddr) != common.AddressLength {
+		return address.Address(""), fmt.Errorf("invalid ethaddress length: expected %v, got %v", common.AddressLength, len(rawAddr))
+	}
+	var addr Address
+	copy(addr[:], rawAddr)
+	if encoder.chainID != nil {
+		return address.Address(addr.ChecksumWithChainID(*encoder.chainID)), nil
 	}
+	return address.Address(addr.Checksum()), nil
 }
 
+// AddressDecoder decodes human-readable addresses into raw addresses.
 type AddressDecoder interface {
 	DecodeAddress(address.Address) (address.RawAddress, error)
 }
 
-type addressDecoder struct{}
+type addressDecoder struct {
+	chainID *pack.U64
+}
 
+// NewAddressDecoder returns an AddressDecoder that rejects mixed-case
+// addresses that do not have a valid EIP-55 checksum.
 func NewAddressDecoder() AddressDecoder {
 	return addressDecoder{}
 }
 
-func NewAddressEncoder() AddressEncoder {
-	return addressEncoder{}
+// NewAddressDecoderWithChainID returns an AddressDecoder that rejects
+// mixed-case addresses that do not have a valid EIP-1191 checksum for the given
+// chain ID.
+func NewAddressDecoderWithChainID(chainID pack.U64) AddressDecoder {
+	return addressDecoder{chainID: &chainID}
 }
 
-func (addressDecoder) DecodeAddress(encoded address.Address) (address.RawAddress, error) {
-	ethaddr, err := NewAddressFromHex(string(pack.String(encoded)))
+func (decoder addressDecoder) DecodeAddress(encoded address.Address) (address.RawAddress, error) {
+	var ethaddr Address
+	var err error
+	if decoder.chainID != nil {
+		ethaddr, err = NewAddressFromHexWithChainID(string(encoded), *decoder.chainID)
+	} else {
+		ethaddr, err = NewAddressFromHex(string(encoded))
+	}
 	if err != nil {
 		return nil, err
 	}
 	return address.RawAddress(pack.Bytes(ethaddr[:])), nil
 }
 
-func (addressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
-	encodedAddr := common.Bytes2Hex([]byte(rawAddr))
-	return address.Address(pack.NewString(encodedAddr)), nil
-}
-
 // An Address represents a public address on the Ethereum blockchain. It can be
 // the address of an external account, or the address of a smart contract.
 type Address common.Address
 
-// NewAddressFromHex returns an Address decoded from a hex
-// string.
+// NewAddressFromHex returns an Address decoded from a hex string. The string
+// can be all lowercase, or all uppercase, but if it is mixed-case then it must
+// have a valid EIP-55 checksum.
 func NewAddressFromHex(str string) (Address, error) {
+	return newAddressFromHex(str, "")
+}
+
+// NewAddressFromHexWithChainID returns an Address decoded from a hex string.
+// The string can be all lowercase, or all uppercase, but if it is mixed-case
+// then it must have a valid EIP-1191 checksum for the given chain ID.
+func NewAddressFromHexWithChainID(str string, chainID pack.U64) (Address, error) {
+	return newAddressFromHex(str, checksumPrefix(chainID))
+}
+
+func newAddressFromHex(str, prefix string) (Address, error) {
 	if strings.HasPrefix(str, "0x") {
 		str = str[2:]
 	}
@@ -74,9 +139,53 @@ func NewAddressFromHex(str string) (Address, error) {
 	if err != nil {
 		return Address{}, fmt.Errorf("invalid ethaddress %v: %v", str, err)
 	}
-	ethaddr := common.Address{}
+	ethaddr := Address{}
 	copy(ethaddr[:], ethaddrData)
-	return Address(ethaddr), nil
+	if str != strings.ToLower(str) && str != strings.ToUpper(str) {
+		if expected := ethaddr.checksum(prefix); str != expected[2:] {
+			return Address{}, fmt.Errorf("invalid ethaddress %v: bad checksum", str)
+		}
+	}
+	return ethaddr, nil
+}
+
+// Checksum returns the address as a 0x prefixed hex string, using the
+// mixed-case checksum defined by EIP-55.
+func (addr Address) Checksum() string {
+	return addr.checksum("")
+}
+
+// ChecksumWithChainID returns the address as a 0x prefixed hex string, using
+// the chain-aware mixed-case checksum defined by EIP-1191. This checksum is not
+// compatible with EIP-55, and should only be used for chains that have adopted
+// it.
+func (addr Address) ChecksumWithChainID(chainID pack.U64) string {
+	return addr.checksum(checksumPrefix(chainID))
+}
+
+// checksumPrefix returns the string that EIP-1191 prepends to the address
+// before it is hashed.
+func checksumPrefix(chainID pack.U64) string {
+	return strconv.FormatUint(chainID.Uint64(), 10) + "0x"
+}
+
+// checksum capitalises each letter of the hex encoded address when the
+// corresponding nibble of the Keccak256 hash of the prefixed address is at
+// least 8.
+func (addr Address) checksum(prefix string) string {
+	lower := hex.EncodeToString(addr[:])
+	hash := crypto.Keccak256([]byte(prefix + lower))
+	result := []byte(lower)
+	for i := range result {
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if result[i] >= 'a' && nibble&0x0f >= 8 {
+			result[i] -= 'a' - 'A'
+		}
+	}
+	return "0x" + string(result)
 }
 
 // SizeHint returns the number of bytes needed to represent this address in
@@ -124,9 +233,10 @@ func (addr *Address) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// String returns the address as a human-readable hex string.
+// String returns the address as a human-readable hex string, with an EIP-55
+// checksum.
 func (addr Address) String() string {
-	return hex.EncodeToString(addr[:])
+	return addr.Checksum()
 }
 
 // Bytes returns the address as a slice of 20 bytes.
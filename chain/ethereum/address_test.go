@@ -3,12 +3,16 @@ package ethereum_test
 import (
 	"encoding/hex"
 	"encoding/json"
+	"strings"
 	"testing/quick"
 
+	"github.com/renproject/multichain/api/address"
 	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
 	"github.com/renproject/surge"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -104,4 +108,74 @@ var _ = Describe("Address", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
+
+	Context("when encoding with a checksum", func() {
+		encodeDecode := func(encoder ethereum.AddressEncoder, decoder ethereum.AddressDecoder, expected string) {
+			raw, err := hex.DecodeString(strings.ToLower(expected[2:]))
+			Expect(err).ToNot(HaveOccurred())
+			encoded, err := encoder.EncodeAddress(address.RawAddress(raw))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoded).To(Equal(address.Address(expected)))
+
+			decoded, err := decoder.DecodeAddress(encoded)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(address.RawAddress(raw)))
+
+			// Lowercase and uppercase addresses have no checksum.
+			_, err = decoder.DecodeAddress(address.Address(strings.ToLower(expected)))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = decoder.DecodeAddress(address.Address("0x" + strings.ToUpper(expected[2:])))
+			Expect(err).ToNot(HaveOccurred())
+
+			// Changing the case of one letter breaks the checksum.
+			broken := []byte(expected)
+			for i := 2; i < len(broken); i++ {
+				if broken[i] >= 'a' && broken[i] <= 'f' {
+					broken[i] -= 'a' - 'A'
+					break
+				}
+			}
+			_, err = decoder.DecodeAddress(address.Address(broken))
+			Expect(err).To(HaveOccurred())
+		}
+
+		DescribeTable("should match EIP-55",
+			func(expected string) {
+				encodeDecode(ethereum.NewAddressEncoder(), ethereum.NewAddressDecoder(), expected)
+
+				addr, err := ethereum.NewAddressFromHex(expected)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr.String()).To(Equal(expected))
+			},
+
+			Entry("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"),
+			Entry("0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"),
+			Entry("0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB"),
+			Entry("0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb", "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb"),
+		)
+
+		DescribeTable("should match EIP-1191",
+			func(chainID uint64, expected string) {
+				encodeDecode(ethereum.NewAddressEncoderWithChainID(pack.NewU64(chainID)), ethereum.NewAddressDecoderWithChainID(pack.NewU64(chainID)), expected)
+
+				// The chain-aware checksum is not an EIP-55 checksum.
+				_, err := ethereum.NewAddressFromHex(expected)
+				Expect(err).To(HaveOccurred())
+			},
+
+			Entry("on RSK mainnet", uint64(30), "0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD"),
+			Entry("on RSK mainnet", uint64(30), "0xFb6916095cA1Df60bb79ce92cE3EA74c37c5d359"),
+			Entry("on RSK mainnet", uint64(30), "0xDBF03B407c01E7CD3cBea99509D93F8Dddc8C6FB"),
+			Entry("on RSK mainnet", uint64(30), "0xD1220A0Cf47c7B9BE7a2e6ba89F429762E7B9adB"),
+			Entry("on RSK testnet", uint64(31), "0x5aAeb6053F3e94c9b9A09F33669435E7EF1BEaEd"),
+			Entry("on RSK testnet", uint64(31), "0xFb6916095CA1dF60bb79CE92ce3Ea74C37c5D359"),
+			Entry("on RSK testnet", uint64(31), "0xdbF03B407C01E7cd3cbEa99509D93f8dDDc8C6fB"),
+			Entry("on RSK testnet", uint64(31), "0xd1220a0CF47c7B9Be7A2E6Ba89f429762E7b9adB"),
+		)
+
+		It("should return an error for a raw address of the wrong length", func() {
+			_, err := ethereum.NewAddressEncoder().EncodeAddress(address.RawAddress{0x01, 0x02})
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,190 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+// A BlockNumber identifies the block against which a contract is called. It is
+// either the number of a historic block, or one of the tags LatestBlock,
+// PendingBlock, or EarliestBlock.
+type BlockNumber int64
+
+// Enumerate the block tags.
+const (
+	PendingBlock  = BlockNumber(-2)
+	LatestBlock   = BlockNumber(-1)
+	EarliestBlock = BlockNumber(0)
+)
+
+// NewBlockNumber returns the BlockNumber of the block at the given height. An
+// error is returned if the height cannot be represented, because it would be
+// confused with one of the block tags.
+func NewBlockNumber(height pack.U64) (BlockNumber, error) {
+	if height.Uint64() > math.MaxInt64 {
+		return 0, fmt.Errorf("bad block number: %v", height)
+	}
+	return BlockNumber(height.Uint64()), nil
+}
+
+// String returns the block tag, or the block number encoded as hex, as
+// expected by the JSON-RPC interface.
+func (block BlockNumber) String() string {
+	switch block {
+	case PendingBlock:
+		return "pending"
+	case LatestBlock:
+		return "latest"
+	case EarliestBlock:
+		return "earliest"
+	default:
+		return hexutil.EncodeUint64(uint64(block))
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (block BlockNumber) MarshalText() ([]byte, error) {
+	if block < PendingBlock {
+		return nil, fmt.Errorf("bad block number: %v", int64(block))
+	}
+	return []byte(block.String()), nil
+}
+
+// The selectors of the errors that Solidity uses to revert.
+var (
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0} // Error(string)
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71} // Panic(uint256)
+)
+
+// RevertError is returned when a call reverts, either by a require or revert
+// statement (in which case the reason is decoded from Error(string)) or
+// without a reason.
+type RevertError struct {
+	Reason string
+	// Data returned by the reverted call, including the selector.
+	Data pack.Bytes
+}
+
+// Error implements the error interface.
+func (err *RevertError) Error() string {
+	if err.Reason == "" {
+		return "execution reverted"
+	}
+	return fmt.Sprintf("execution reverted: %v", err.Reason)
+}
+
+// PanicError is returned when a call reverts because of a failed assertion, or
+// another runtime error (in which case the code is decoded from
+// Panic(uint256)).
+type PanicError struct {
+	Code pack.U256
+}
+
+// panicReasons describes the codes used by Solidity when it panics.
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array",
+	0x31: "pop from empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero-initialized function",
+}
+
+// Error implements the error interface.
+func (err *PanicError) Error() string {
+	code := uint64(0)
+	if !err.Code.Equal(pack.U256{}) {
+		if !err.Code.Int().IsUint64() {
+			return fmt.Sprintf("execution reverted: panic %v", err.Code)
+		}
+		code = err.Code.Int().Uint64()
+	}
+	if reason, ok := panicReasons[code]; ok {
+		return fmt.Sprintf("execution reverted: panic 0x%02x (%v)", code, reason)
+	}
+	return fmt.Sprintf("execution reverted: panic 0x%02x", code)
+}
+
+// CallContractAt calls the contract at the specified address, using the
+// specified calldata as input, against the state of the block at the specified
+// height. It implements the contract.CallerAt interface.
+func (client *Client) CallContractAt(ctx context.Context, contractAddr address.Address, calldata contract.CallData, height pack.U64) (pack.Bytes, error) {
+	block, err := NewBlockNumber(height)
+	if err != nil {
+		return nil, err
+	}
+	return client.CallContractAtBlock(ctx, contractAddr, calldata, block)
+}
+
+// CallContractAtBlock calls the contract at the specified address, using the
+// specified calldata as input, against the state of the specified block (which
+// can also be one of the block tags). Calling against a historic block
+// requires an archive node, unless the block is recent. If the call reverts,
+// then a *RevertError or *PanicError is returned.
+func (client *Client) CallContractAtBlock(ctx context.Context, contractAddr address.Address, calldata contract.CallData, block BlockNumber) (pack.Bytes, error) {
+	to, err := NewAddressFromHex(string(contractAddr))
+	if err != nil {
+		return nil, fmt.Errorf("bad contract address: %v", err)
+	}
+	msg := map[string]interface{}{
+		"to":   common.Address(to),
+		"data": hexutil.Bytes(calldata),
+	}
+	output := hexutil.Bytes{}
+	if err := client.rpcClient.CallContext(ctx, &output, "eth_call", msg, block); err != nil {
+		if revertErr := decodeRevert(err); revertErr != nil {
+			return nil, revertErr
+		}
+		return nil, fmt.Errorf("bad \"eth_call\": %v", err)
+	}
+	return pack.NewBytes(output), nil
+}
+
+// decodeRevert returns a *RevertError or *PanicError if the error was returned
+// by a reverted call, and nil otherwise. Reverted calls are identified by the
+// data of the error, which holds the (possibly empty) output of the call. Nodes
+// that do not return the output of reverted calls report them with the bare
+// "execution reverted" message instead.
+func decodeRevert(err error) error {
+	hexData := ""
+	if dataErr, ok := err.(rpc.DataError); ok {
+		hexData, _ = dataErr.ErrorData().(string)
+	}
+	if hexData == "" {
+		if err.Error() == "execution reverted" {
+			return &RevertError{}
+		}
+		return nil
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return nil
+	}
+
+	switch {
+	case len(data) >= 4 && bytes.Equal(data[:4], errorSelector):
+		var reason pack.String
+		if Decode(data[4:], &reason) == nil {
+			return &RevertError{Reason: string(reason), Data: pack.NewBytes(data)}
+		}
+	case len(data) >= 4 && bytes.Equal(data[:4], panicSelector):
+		var code pack.U256
+		if Decode(data[4:], &code) == nil {
+			return &PanicError{Code: code}
+		}
+	}
+	return &RevertError{Data: pack.NewBytes(data)}
+}
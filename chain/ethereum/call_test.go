@@ -0,0 +1,175 @@
+package ethereum_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Call", func() {
+	to := common.HexToAddress("0x797522Fb74d42bB9fbF6b76dEa24D01A538d5D66")
+
+	DescribeTable("when calling a contract at a block",
+		func(block ethereum.BlockNumber, expected string) {
+			server := newStandIn(map[string]handler{
+				"eth_call": func(params []json.RawMessage) (interface{}, error) {
+					Expect(params).To(HaveLen(2))
+					tag := ""
+					Expect(json.Unmarshal(params[1], &tag)).To(Succeed())
+					Expect(tag).To(Equal(expected))
+					return hexutil.Bytes{0x01}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			output, err := client.CallContractAtBlock(context.Background(), address.Address(to.Hex()), contract.CallData{0x01}, block)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output).To(Equal(pack.Bytes{0x01}))
+		},
+
+		Entry("should use the latest block", ethereum.LatestBlock, "latest"),
+		Entry("should use the pending block", ethereum.PendingBlock, "pending"),
+		Entry("should use the earliest block", ethereum.EarliestBlock, "earliest"),
+		Entry("should use a historic block", ethereum.BlockNumber(100), "0x64"),
+	)
+
+	Context("when calling a contract at a height", func() {
+		It("should implement the contract.CallerAt interface", func() {
+			server := newStandIn(map[string]handler{
+				"eth_call": func(params []json.RawMessage) (interface{}, error) {
+					Expect(string(params[1])).To(Equal(`"0x64"`))
+					return hexutil.Bytes{0x01}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			var caller contract.CallerAt = client
+			output, err := caller.CallContractAt(context.Background(), address.Address(to.Hex()), contract.CallData{0x01}, pack.NewU64(100))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output).To(Equal(pack.Bytes{0x01}))
+		})
+
+		It("should return an error for heights that cannot be represented", func() {
+			block, err := ethereum.NewBlockNumber(pack.NewU64(math.MaxInt64))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(block.String()).To(Equal("0x7fffffffffffffff"))
+
+			_, err = ethereum.NewBlockNumber(pack.NewU64(math.MaxInt64 + 1))
+			Expect(err).To(HaveOccurred())
+			_, err = ethereum.NewBlockNumber(pack.NewU64(math.MaxUint64))
+			Expect(err).To(HaveOccurred())
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.CallContractAt(context.Background(), address.Address(to.Hex()), contract.CallData{0x01}, pack.NewU64(math.MaxUint64))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the call reverts", func() {
+		call := func(err error) error {
+			server := newStandIn(map[string]handler{
+				"eth_call": func(params []json.RawMessage) (interface{}, error) {
+					return nil, err
+				},
+			})
+			defer server.Close()
+
+			client, clientErr := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(clientErr).ToNot(HaveOccurred())
+			_, callErr := client.CallContract(context.Background(), address.Address(to.Hex()), contract.CallData{0x01})
+			return callErr
+		}
+
+		It("should decode the reason of an Error(string)", func() {
+			encoded, err := ethereum.Encode(pack.String("insufficient balance"))
+			Expect(err).ToNot(HaveOccurred())
+			data := append([]byte{0x08, 0xc3, 0x79, 0xa0}, encoded...)
+
+			err = call(dataError{message: "execution reverted: insufficient balance", data: data})
+			revertErr, ok := err.(*ethereum.RevertError)
+			Expect(ok).To(BeTrue())
+			Expect(revertErr.Reason).To(Equal("insufficient balance"))
+			Expect(revertErr.Data).To(Equal(pack.Bytes(data)))
+			Expect(revertErr.Error()).To(Equal("execution reverted: insufficient balance"))
+		})
+
+		It("should decode the code of a Panic(uint256)", func() {
+			encoded, err := ethereum.Encode(pack.NewU256FromU64(pack.NewU64(0x11)))
+			Expect(err).ToNot(HaveOccurred())
+			data := append([]byte{0x4e, 0x48, 0x7b, 0x71}, encoded...)
+
+			err = call(dataError{message: "execution reverted", data: data})
+			panicErr, ok := err.(*ethereum.PanicError)
+			Expect(ok).To(BeTrue())
+			Expect(panicErr.Code).To(Equal(pack.NewU256FromU64(pack.NewU64(0x11))))
+			Expect(panicErr.Error()).To(ContainSubstring("arithmetic overflow"))
+		})
+
+		It("should return a revert error without a reason when there is no data", func() {
+			err := call(dataError{message: "execution reverted"})
+			revertErr, ok := err.(*ethereum.RevertError)
+			Expect(ok).To(BeTrue())
+			Expect(revertErr.Reason).To(BeEmpty())
+			Expect(revertErr.Data).To(BeEmpty())
+		})
+
+		It("should return a revert error when the node only reports the message", func() {
+			err := call(fmt.Errorf("execution reverted"))
+			revertErr, ok := err.(*ethereum.RevertError)
+			Expect(ok).To(BeTrue())
+			Expect(revertErr.Reason).To(BeEmpty())
+			Expect(revertErr.Data).To(BeEmpty())
+		})
+	})
+
+	Context("when the call fails for another reason", func() {
+		It("should not return a revert error", func() {
+			server := newStandIn(map[string]handler{
+				"eth_call": func(params []json.RawMessage) (interface{}, error) {
+					return nil, fmt.Errorf("missing trie node")
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.CallContractAt(context.Background(), address.Address(to.Hex()), contract.CallData{0x01}, pack.NewU64(1))
+			Expect(err).To(HaveOccurred())
+			_, ok := err.(*ethereum.RevertError)
+			Expect(ok).To(BeFalse())
+
+			// Messages that mention a revert are not enough, because only
+			// the data of the error (or the bare "execution reverted"
+			// message) identifies a reverted call.
+			server = newStandIn(map[string]handler{
+				"eth_call": func(params []json.RawMessage) (interface{}, error) {
+					return nil, fmt.Errorf("execution reverted: paused")
+				},
+			})
+			defer server.Close()
+			client, err = ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.CallContract(context.Background(), address.Address(to.Hex()), contract.CallData{0x01})
+			Expect(err).To(HaveOccurred())
+			_, ok = err.(*ethereum.RevertError)
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,420 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+// DomainType is the name of the type of the domain of EIP-712 typed data.
+const DomainType = "EIP712Domain"
+
+// A TypedDataField is a named and typed member of an EIP-712 struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is EIP-712 typed structured data. It is usually signed off-chain,
+// and verified on-chain (for example, orders and permits). Its JSON encoding is
+// the same as the one used by "eth_signTypedData_v4".
+//
+// Values in the domain and message can be given as Go values or JSON values:
+// integers can be Go integers, *big.Int, pack.U8, ..., pack.U256, json.Number,
+// or decimal or 0x prefixed hex strings; addresses can be Address,
+// address.Address, or hex strings; bytes can be pack.Bytes, []byte,
+// pack.Bytes32, or hex strings; structs are map[string]interface{}; and arrays
+// are []interface{}.
+type TypedData struct {
+	// Types of all structs, by name. This must include the DomainType.
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Numbers are decoded
+// as json.Number, so that large integers do not lose precision.
+func (data *TypedData) UnmarshalJSON(raw []byte) error {
+	type typedData TypedData
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode((*typedData)(data))
+}
+
+// Digest returns the EIP-712 digest of the typed data. This is the digest that
+// is signed, in the same way as the sighashes of a transaction.
+func (data TypedData) Digest() (pack.Bytes32, error) {
+	domainSeparator, err := data.DomainSeparator()
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	messageHash, err := data.HashStruct(data.PrimaryType, data.Message)
+	if err != nil {
+		return pack.Bytes32{}, fmt.Errorf("bad message: %v", err)
+	}
+	return TypedDataDigest(domainSeparator, messageHash), nil
+}
+
+// DomainSeparator returns the hash of the domain of the typed data.
+func (data TypedData) DomainSeparator() (pack.Bytes32, error) {
+	domainSeparator, err := data.HashStruct(DomainType, data.Domain)
+	if err != nil {
+		return pack.Bytes32{}, fmt.Errorf("bad domain: %v", err)
+	}
+	return domainSeparator, nil
+}
+
+// Signer returns the address that produced the signature over the digest of
+// the typed data. The signature must be in the 65 byte [R || S || V] format,
+// where V is the recovery ID (0 or 1, although 27 and 28 are also accepted).
+func (data TypedData) Signer(signature pack.Bytes65) (Address, error) {
+	digest, err := data.Digest()
+	if err != nil {
+		return Address{}, err
+	}
+	return RecoverAddress(digest, signature)
+}
+
+// TypedDataDigest returns the EIP-712 digest of a message, given the domain
+// separator and the hash of the message struct. Use this when the domain
+// separator is already known (for example, when it has been read from the
+// verifying contract).
+func TypedDataDigest(domainSeparator, messageHash pack.Bytes32) pack.Bytes32 {
+	return pack.NewBytes32(crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator[:], messageHash[:]))
+}
+
+// RecoverAddress returns the address of the public key that produced the
+// signature over the digest. The signature must be in the 65 byte [R || S || V]
+// format, where V is the recovery ID (0 or 1, although 27 and 28 are also
+// accepted).
+func RecoverAddress(digest pack.Bytes32, signature pack.Bytes65) (Address, error) {
+	sig, err := normaliseSignature(signature)
+	if err != nil {
+		return Address{}, err
+	}
+	signer, err := recoverSender(digest, sig)
+	if err != nil {
+		return Address{}, fmt.Errorf("bad signature: %v", err)
+	}
+	return signer, nil
+}
+
+// HashStruct returns the hash of the struct value, which has the named type.
+func (data TypedData) HashStruct(typeName string, value map[string]interface{}) (pack.Bytes32, error) {
+	encoded, err := data.encodeData(typeName, value)
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	return pack.NewBytes32(crypto.Keccak256Hash(encoded)), nil
+}
+
+// TypeHash returns the hash of the encoding of the named type.
+func (data TypedData) TypeHash(typeName string) (pack.Bytes32, error) {
+	encoded, err := data.EncodeType(typeName)
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	return pack.NewBytes32(crypto.Keccak256Hash([]byte(encoded))), nil
+}
+
+// EncodeType returns the encoding of the named type. For example,
+// "Mail(Person from,Person to,string contents)Person(string name,address wallet)".
+// The named type comes first, followed by the types that it references, sorted
+// by name.
+func (data TypedData) EncodeType(typeName string) (string, error) {
+	deps := map[string]bool{}
+	if err := data.dependencies(typeName, deps); err != nil {
+		return "", err
+	}
+	delete(deps, typeName)
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = append([]string{typeName}, names...)
+
+	encoded := strings.Builder{}
+	for _, name := range names {
+		encoded.WriteString(name)
+		encoded.WriteString("(")
+		for i, field := range data.Types[name] {
+			if i > 0 {
+				encoded.WriteString(",")
+			}
+			encoded.WriteString(field.Type)
+			encoded.WriteString(" ")
+			encoded.WriteString(field.Name)
+		}
+		encoded.WriteString(")")
+	}
+	return encoded.String(), nil
+}
+
+// dependencies adds the named type, and all of the struct types that it
+// references, to the set of dependencies.
+func (data TypedData) dependencies(typeName string, deps map[string]bool) error {
+	if deps[typeName] {
+		return nil
+	}
+	fields, ok := data.Types[typeName]
+	if !ok {
+		return fmt.Errorf("bad type: %v not found", typeName)
+	}
+	deps[typeName] = true
+	for _, field := range fields {
+		elemType := field.Type
+		if i := strings.Index(elemType, "["); i >= 0 {
+			elemType = elemType[:i]
+		}
+		if _, ok := data.Types[elemType]; ok {
+			if err := data.dependencies(elemType, deps); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeData returns the type hash of the struct type, followed by the encoding
+// of each field of the struct value.
+func (data TypedData) encodeData(typeName string, value map[string]interface{}) ([]byte, error) {
+	typeHash, err := data.TypeHash(typeName)
+	if err != nil {
+		return nil, err
+	}
+	encoded := append([]byte{}, typeHash[:]...)
+	for _, field := range data.Types[typeName] {
+		fieldValue, ok := value[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("bad %v: missing field %v", typeName, field.Name)
+		}
+		word, err := data.encodeField(field.Type, fieldValue)
+		if err != nil {
+			return nil, fmt.Errorf("bad %v.%v: %v", typeName, field.Name, err)
+		}
+		encoded = append(encoded, word...)
+	}
+	return encoded, nil
+}
+
+// arrayType matches array types, and captures the element type and length (if
+// the array has a fixed length).
+var arrayType = regexp.MustCompile(`^(.+)\[([0-9]*)\]$`)
+
+// encodeField returns the 32 byte encoding of a value of the given type. Structs,
+// arrays, and dynamic types are encoded as the hash of their contents.
+func (data TypedData) encodeField(ty string, value interface{}) ([]byte, error) {
+	if match := arrayType.FindStringSubmatch(ty); match != nil {
+		elems, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array, got %T", value)
+		}
+		if match[2] != "" {
+			n, err := strconv.Atoi(match[2])
+			if err != nil || n != len(elems) {
+				return nil, fmt.Errorf("expected %v elements, got %v elements", match[2], len(elems))
+			}
+		}
+		encoded := []byte{}
+		for i, elem := range elems {
+			word, err := data.encodeField(match[1], elem)
+			if err != nil {
+				return nil, fmt.Errorf("bad element %v: %v", i, err)
+			}
+			encoded = append(encoded, word...)
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	if _, ok := data.Types[ty]; ok {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected struct, got %T", value)
+		}
+		hash, err := data.HashStruct(ty, fields)
+		if err != nil {
+			return nil, err
+		}
+		return hash[:], nil
+	}
+	return encodeAtomic(ty, value)
+}
+
+// encodeAtomic returns the 32 byte encoding of a value of an atomic type, or of
+// the dynamic types (string and bytes), which are encoded as their hash.
+func encodeAtomic(ty string, value interface{}) ([]byte, error) {
+	switch {
+	case ty == "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+	case ty == "bytes":
+		b, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(b), nil
+	case ty == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		word := make([]byte, 32)
+		if b {
+			word[31] = 1
+		}
+		return word, nil
+	case ty == "address":
+		addr, err := typedAddress(value)
+		if err != nil {
+			return nil, err
+		}
+		return common.LeftPadBytes(addr[:], 32), nil
+	case strings.HasPrefix(ty, "bytes"):
+		size, err := strconv.Atoi(ty[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unsupported type %v", ty)
+		}
+		b, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != size {
+			return nil, fmt.Errorf("expected %v bytes, got %v bytes", size, len(b))
+		}
+		return common.RightPadBytes(b, 32), nil
+	case strings.HasPrefix(ty, "uint"), strings.HasPrefix(ty, "int"):
+		signed := strings.HasPrefix(ty, "int")
+		bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(ty, "u"), "int"))
+		if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("unsupported type %v", ty)
+		}
+		x, err := typedInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if signed {
+			min := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(bits-1)))
+			max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), big.NewInt(1))
+			if x.Cmp(min) < 0 || x.Cmp(max) > 0 {
+				return nil, fmt.Errorf("expected %v, got %v", ty, x)
+			}
+			return encodeInt(x)
+		}
+		if x.Sign() < 0 || x.BitLen() > bits {
+			return nil, fmt.Errorf("expected %v, got %v", ty, x)
+		}
+		return encodeUint(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %v", ty)
+	}
+}
+
+// typedBytes converts a value into bytes.
+func typedBytes(value interface{}) ([]byte, error) {
+	switch value := value.(type) {
+	case pack.Bytes:
+		return value, nil
+	case []byte:
+		return value, nil
+	case pack.Bytes32:
+		return value[:], nil
+	case string:
+		b, err := hexutil.Decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad hex: %v", err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("expected bytes, got %T", value)
+	}
+}
+
+// typedAddress converts a value into an address.
+func typedAddress(value interface{}) (Address, error) {
+	switch value := value.(type) {
+	case Address:
+		return value, nil
+	case common.Address:
+		return Address(value), nil
+	case address.Address:
+		return NewAddressFromHex(string(value))
+	case string:
+		return NewAddressFromHex(value)
+	default:
+		return Address{}, fmt.Errorf("expected address, got %T", value)
+	}
+}
+
+// typedInt converts a value into an integer.
+func typedInt(value interface{}) (*big.Int, error) {
+	switch value := value.(type) {
+	case *big.Int:
+		if value == nil {
+			return nil, fmt.Errorf("expected integer, got nil")
+		}
+		return value, nil
+	case int:
+		return big.NewInt(int64(value)), nil
+	case int8:
+		return big.NewInt(int64(value)), nil
+	case int16:
+		return big.NewInt(int64(value)), nil
+	case int32:
+		return big.NewInt(int64(value)), nil
+	case int64:
+		return big.NewInt(value), nil
+	case uint:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case uint8:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case uint16:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case uint32:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case uint64:
+		return new(big.Int).SetUint64(value), nil
+	case pack.U8:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case pack.U16:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case pack.U32:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case pack.U64:
+		return new(big.Int).SetUint64(uint64(value)), nil
+	case pack.U128:
+		return value.Int(), nil
+	case pack.U256:
+		return value.Int(), nil
+	case float64:
+		x, accuracy := big.NewFloat(value).Int(nil)
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("expected integer, got %v", value)
+		}
+		return x, nil
+	case json.Number:
+		return typedInt(string(value))
+	case string:
+		x, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, fmt.Errorf("expected integer, got %q", value)
+		}
+		return x, nil
+	default:
+		return nil, fmt.Errorf("expected integer, got %T", value)
+	}
+}
@@ -0,0 +1,239 @@
+package ethereum_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mailJSON is the example typed data from EIP-712.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// groupMailJSON extends the example with arrays, nested arrays of structs, and
+// fixed-size bytes and signed integers.
+const groupMailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "salt", "type": "bytes32"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallets", "type": "address[]"}
+		],
+		"Group": [
+			{"name": "name", "type": "string"},
+			{"name": "members", "type": "Person[]"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Group"},
+			{"name": "contents", "type": "string"},
+			{"name": "attachment", "type": "bytes"},
+			{"name": "priority", "type": "int8"},
+			{"name": "nonce", "type": "uint256"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"chainId": "0x1",
+		"salt": "0xf2d857f4a3edcb9b78b4d503bfe733db1e3f6cdc2b7971ee739626c97e86a558"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallets": ["0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", "0xDeaDbeefdEAdbeefdEadbEEFdeadbeEFdEaDbeeF"]},
+		"to": {
+			"name": "Friends",
+			"members": [
+				{"name": "Bob", "wallets": ["0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"]},
+				{"name": "Alice", "wallets": []}
+			]
+		},
+		"contents": "Hello, Bob!",
+		"attachment": "0xcafe",
+		"priority": -1,
+		"nonce": "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+	}
+}`
+
+var _ = Describe("EIP-712", func() {
+	Context("when hashing the example from the EIP", func() {
+		typedData := ethereum.TypedData{}
+		Expect(json.Unmarshal([]byte(mailJSON), &typedData)).To(Succeed())
+
+		It("should encode the types", func() {
+			encoded, err := typedData.EncodeType("Mail")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoded).To(Equal("Mail(Person from,Person to,string contents)Person(string name,address wallet)"))
+
+			typeHash, err := typedData.TypeHash("Mail")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hexutil.Encode(typeHash[:])).To(Equal("0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"))
+		})
+
+		It("should hash the domain and message", func() {
+			domainSeparator, err := typedData.DomainSeparator()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hexutil.Encode(domainSeparator[:])).To(Equal("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"))
+
+			messageHash, err := typedData.HashStruct("Mail", typedData.Message)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hexutil.Encode(messageHash[:])).To(Equal("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"))
+
+			digest, err := typedData.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hexutil.Encode(digest[:])).To(Equal("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"))
+			Expect(ethereum.TypedDataDigest(domainSeparator, messageHash)).To(Equal(digest))
+		})
+
+		It("should recover the signer", func() {
+			// The signature from the EIP, produced by the private key
+			// keccak256("cow").
+			sig := pack.Bytes65{}
+			r, _ := hex.DecodeString("4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d")
+			s, _ := hex.DecodeString("07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562")
+			copy(sig[:32], r)
+			copy(sig[32:64], s)
+			sig[64] = 28
+
+			signer, err := typedData.Signer(sig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer.String()).To(Equal("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"))
+
+			// The same signature can be produced by the transaction signer.
+			privKey, err := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+			Expect(err).ToNot(HaveOccurred())
+			digest, err := typedData.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			signature, err := crypto.Sign(digest[:], privKey)
+			Expect(err).ToNot(HaveOccurred())
+			rsv := pack.Bytes65{}
+			copy(rsv[:], signature)
+			signer, err = ethereum.RecoverAddress(digest, rsv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer.String()).To(Equal("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"))
+		})
+	})
+
+	Context("when hashing arrays and nested types", func() {
+		typedData := ethereum.TypedData{}
+		Expect(json.Unmarshal([]byte(groupMailJSON), &typedData)).To(Succeed())
+		reference := core.TypedData{}
+		Expect(json.Unmarshal([]byte(groupMailJSON), &reference)).To(Succeed())
+
+		// hashStruct of a struct in the message, as computed by go-ethereum.
+		referenceHash := func(typeName string, value interface{}) []byte {
+			hash, err := reference.HashStruct(typeName, value.(map[string]interface{}))
+			Expect(err).ToNot(HaveOccurred())
+			return hash
+		}
+
+		It("should match go-ethereum for arrays of atomic types", func() {
+			domainSeparator, err := typedData.DomainSeparator()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(domainSeparator[:]).To(Equal(referenceHash("EIP712Domain", reference.Domain.Map())))
+
+			from, err := typedData.HashStruct("Person", typedData.Message["from"].(map[string]interface{}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(from[:]).To(Equal(referenceHash("Person", reference.Message["from"])))
+		})
+
+		It("should hash each struct in an array", func() {
+			// The go-ethereum version in use does not hash structs in arrays, so
+			// the expected hashes are built from the hashes of each struct.
+			group := reference.Message["to"].(map[string]interface{})
+			members := group["members"].([]interface{})
+			groupTypeHash, err := typedData.TypeHash("Group")
+			Expect(err).ToNot(HaveOccurred())
+			expectedGroup := crypto.Keccak256(
+				groupTypeHash[:],
+				crypto.Keccak256([]byte("Friends")),
+				crypto.Keccak256(referenceHash("Person", members[0]), referenceHash("Person", members[1])),
+			)
+			groupHash, err := typedData.HashStruct("Group", typedData.Message["to"].(map[string]interface{}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(groupHash[:]).To(Equal(expectedGroup))
+
+			encoded, err := typedData.EncodeType("Mail")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoded).To(Equal("Mail(Person from,Group to,string contents,bytes attachment,int8 priority,uint256 nonce)Group(string name,Person[] members)Person(string name,address[] wallets)"))
+			mailTypeHash, err := typedData.TypeHash("Mail")
+			Expect(err).ToNot(HaveOccurred())
+			// Both -1 (as an int8) and 2^256 - 1 are encoded as a word of ones.
+			ones := bytes.Repeat([]byte{0xff}, 32)
+			expectedMail := crypto.Keccak256(
+				mailTypeHash[:],
+				referenceHash("Person", reference.Message["from"]),
+				expectedGroup,
+				crypto.Keccak256([]byte("Hello, Bob!")),
+				crypto.Keccak256([]byte{0xca, 0xfe}),
+				ones,
+				ones,
+			)
+			mailHash, err := typedData.HashStruct("Mail", typedData.Message)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mailHash[:]).To(Equal(expectedMail))
+		})
+	})
+
+	Context("when the typed data is invalid", func() {
+		It("should return an error", func() {
+			typedData := ethereum.TypedData{}
+			Expect(json.Unmarshal([]byte(mailJSON), &typedData)).To(Succeed())
+
+			// Missing field.
+			_, err := typedData.HashStruct("Person", map[string]interface{}{"name": "Cow"})
+			Expect(err).To(HaveOccurred())
+			// Unknown type.
+			_, err = typedData.HashStruct("Letter", map[string]interface{}{})
+			Expect(err).To(HaveOccurred())
+			// Bad address.
+			_, err = typedData.HashStruct("Person", map[string]interface{}{"name": "Cow", "wallet": "0x1234"})
+			Expect(err).To(HaveOccurred())
+
+			typedData.Types["Person"][1].Type = "uint8"
+			_, err = typedData.HashStruct("Person", map[string]interface{}{"name": "Cow", "wallet": 256})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -3,8 +3,8 @@ package ethereum
 import (
 	"fmt"
 	"math/big"
+	"reflect"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/renproject/pack"
 )
 
@@ -17,63 +17,434 @@ type Payload struct {
 	Data pack.Bytes `json:"data"`
 }
 
-// Encode values into an Ethereum ABI compatible byte slice.
-func Encode(vals ...interface{}) []byte {
-	ethargs := make(abi.Arguments, 0, len(vals))
-	ethvals := make([]interface{}, 0, len(vals))
+// wordSize is the number of bytes in an ABI word. All values are encoded in
+// one or more words.
+const wordSize = 32
 
+var (
+	typeBool    = reflect.TypeOf(pack.Bool(false))
+	typeString  = reflect.TypeOf(pack.String(""))
+	typeBytes   = reflect.TypeOf(pack.Bytes{})
+	typeBytes32 = reflect.TypeOf(pack.Bytes32{})
+	typeU8      = reflect.TypeOf(pack.U8(0))
+	typeU16     = reflect.TypeOf(pack.U16(0))
+	typeU32     = reflect.TypeOf(pack.U32(0))
+	typeU64     = reflect.TypeOf(pack.U64(0))
+	typeU128    = reflect.TypeOf(pack.U128{})
+	typeU256    = reflect.TypeOf(pack.U256{})
+	typeAddress = reflect.TypeOf(Address{})
+	typeBigInt  = reflect.TypeOf((*big.Int)(nil))
+	typeRaw     = reflect.TypeOf([]byte{})
+
+	maxU256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	maxI256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+	minI256 = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 255))
+)
+
+// Encode values into an Ethereum ABI compatible byte slice. The values are
+// encoded as if they were the parameters of a function call (without the
+// function selector). The following types are supported:
+//
+//	pack.Bool                   bool
+//	pack.String                 string
+//	pack.Bytes, []byte          bytes
+//	pack.Bytes32                bytes32
+//	pack.U8, ..., pack.U256     uint256
+//	int8, ..., int64, *big.Int  int256
+//	Address                     address
+//	[N]T                        T[N]
+//	[]T                         T[]
+//	struct{...}                 tuple (using exported fields, in order)
+//
+// An error is returned if any value has an unsupported type, or cannot be
+// represented by its ABI type.
+func Encode(vals ...interface{}) ([]byte, error) {
+	rvals := make([]reflect.Value, len(vals))
+	for i, val := range vals {
+		if val == nil {
+			return nil, fmt.Errorf("encoding arg %v: nil value", i)
+		}
+		rvals[i] = reflect.ValueOf(val)
+	}
+	return encodeTuple(rvals)
+}
+
+// Decode Ethereum ABI compatible data into values. The data is decoded as if it
+// was the return data of a function call. Each value must be a non-nil pointer
+// to one of the types supported by Encode. Unsigned integers are range checked
+// against the type into which they are being decoded.
+func Decode(data []byte, vals ...interface{}) error {
+	rvals := make([]reflect.Value, len(vals))
+	for i, val := range vals {
+		rval := reflect.ValueOf(val)
+		if rval.Kind() != reflect.Ptr || rval.IsNil() {
+			return fmt.Errorf("decoding arg %v: expected non-nil pointer, got %T", i, val)
+		}
+		rvals[i] = rval.Elem()
+	}
+	return decodeTuple(data, rvals)
+}
+
+// isDynamic returns true if the ABI type of the Go type is dynamically sized
+// (in which case its encoding is referenced by an offset).
+func isDynamic(ty reflect.Type) (bool, error) {
+	switch ty {
+	case typeString, typeBytes, typeRaw:
+		return true, nil
+	case typeBool, typeBytes32, typeU8, typeU16, typeU32, typeU64, typeU128, typeU256, typeAddress, typeBigInt:
+		return false, nil
+	}
+	switch ty.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return false, nil
+	case reflect.Slice:
+		return true, nil
+	case reflect.Array:
+		return isDynamic(ty.Elem())
+	case reflect.Struct:
+		for i := 0; i < ty.NumField(); i++ {
+			if ty.Field(i).PkgPath != "" {
+				continue
+			}
+			dynamic, err := isDynamic(ty.Field(i).Type)
+			if err != nil || dynamic {
+				return dynamic, err
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported type %v", ty)
+}
+
+// headSize returns the number of bytes used by the Go type in the head of a
+// tuple encoding.
+func headSize(ty reflect.Type) (int, error) {
+	dynamic, err := isDynamic(ty)
+	if err != nil {
+		return 0, err
+	}
+	if dynamic {
+		return wordSize, nil
+	}
+	switch ty {
+	case typeBytes32, typeAddress, typeBigInt, typeU128, typeU256:
+		return wordSize, nil
+	}
+	switch ty.Kind() {
+	case reflect.Array:
+		elemSize, err := headSize(ty.Elem())
+		if err != nil {
+			return 0, err
+		}
+		return ty.Len() * elemSize, nil
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < ty.NumField(); i++ {
+			if ty.Field(i).PkgPath != "" {
+				continue
+			}
+			fieldSize, err := headSize(ty.Field(i).Type)
+			if err != nil {
+				return 0, err
+			}
+			size += fieldSize
+		}
+		return size, nil
+	}
+	return wordSize, nil
+}
+
+// fields returns the exported fields of a struct value.
+func fields(val reflect.Value) []reflect.Value {
+	fs := make([]reflect.Value, 0, val.NumField())
+	for i := 0; i < val.NumField(); i++ {
+		if val.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		fs = append(fs, val.Field(i))
+	}
+	return fs
+}
+
+// elems returns the elements of an array or slice value.
+func elems(val reflect.Value) []reflect.Value {
+	es := make([]reflect.Value, val.Len())
+	for i := range es {
+		es[i] = val.Index(i)
+	}
+	return es
+}
+
+func encodeTuple(vals []reflect.Value) ([]byte, error) {
+	headLen := 0
 	for _, val := range vals {
-		var ethval interface{}
-		var ty abi.Type
-		var err error
-
-		switch val := val.(type) {
-		case pack.Bytes:
-			ethval = val
-			ty, err = abi.NewType("bytes", "", nil)
-		case pack.Bytes32:
-			ethval = val
-			ty, err = abi.NewType("bytes32", "", nil)
-
-		case pack.U8:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint8()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U16:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint16()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U32:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint32()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U64:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint64()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U128:
-			ethval = val.Int()
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U256:
-			ethval = val.Int()
-			ty, err = abi.NewType("uint256", "", nil)
-
-		case Address:
-			ethval = val
-			ty, err = abi.NewType("address", "", nil)
-
-		default:
-			panic(fmt.Errorf("non-exhaustive pattern: %T", val))
+		size, err := headSize(val.Type())
+		if err != nil {
+			return nil, err
 		}
+		headLen += size
+	}
 
+	head := make([]byte, 0, headLen)
+	tail := []byte{}
+	for _, val := range vals {
+		encoded, err := encodeValue(val)
+		if err != nil {
+			return nil, err
+		}
+		dynamic, err := isDynamic(val.Type())
 		if err != nil {
-			panic(fmt.Errorf("error encoding: %v", err))
+			return nil, err
+		}
+		if dynamic {
+			head = append(head, encodeUint(big.NewInt(int64(headLen+len(tail))))...)
+			tail = append(tail, encoded...)
+			continue
 		}
-		ethargs = append(ethargs, abi.Argument{
-			Type: ty,
-		})
-		ethvals = append(ethvals, ethval)
+		head = append(head, encoded...)
 	}
+	return append(head, tail...), nil
+}
 
-	packed, err := ethargs.Pack(ethvals...)
-	if err != nil {
-		panic(fmt.Errorf("error packing: %v", err))
+func encodeValue(val reflect.Value) ([]byte, error) {
+	switch val.Type() {
+	case typeBool:
+		if val.Bool() {
+			return encodeUint(big.NewInt(1)), nil
+		}
+		return encodeUint(big.NewInt(0)), nil
+	case typeString:
+		return encodeBytes([]byte(val.String())), nil
+	case typeBytes, typeRaw:
+		return encodeBytes(val.Bytes()), nil
+	case typeBytes32:
+		bytes32 := val.Interface().(pack.Bytes32)
+		return bytes32[:], nil
+	case typeU8, typeU16, typeU32, typeU64:
+		return encodeUint(new(big.Int).SetUint64(val.Uint())), nil
+	case typeU128:
+		u128 := val.Interface().(pack.U128)
+		if u128.Equal(pack.U128{}) {
+			// The zero value of a U128 has no underlying integer.
+			return encodeUint(big.NewInt(0)), nil
+		}
+		return encodeUint(u128.Int()), nil
+	case typeU256:
+		u256 := val.Interface().(pack.U256)
+		if u256.Equal(pack.U256{}) {
+			// The zero value of a U256 has no underlying integer.
+			return encodeUint(big.NewInt(0)), nil
+		}
+		return encodeUint(u256.Int()), nil
+	case typeAddress:
+		addr := val.Interface().(Address)
+		word := make([]byte, wordSize)
+		copy(word[wordSize-len(addr):], addr[:])
+		return word, nil
+	case typeBigInt:
+		if val.IsNil() {
+			return nil, fmt.Errorf("unsupported value: nil %v", val.Type())
+		}
+		return encodeInt(val.Interface().(*big.Int))
+	}
+
+	switch val.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodeInt(big.NewInt(val.Int()))
+	case reflect.Array:
+		return encodeTuple(elems(val))
+	case reflect.Slice:
+		encoded, err := encodeTuple(elems(val))
+		if err != nil {
+			return nil, err
+		}
+		return append(encodeUint(big.NewInt(int64(val.Len()))), encoded...), nil
+	case reflect.Struct:
+		return encodeTuple(fields(val))
+	}
+	return nil, fmt.Errorf("unsupported type %v", val.Type())
+}
+
+func encodeUint(x *big.Int) []byte {
+	word := make([]byte, wordSize)
+	bytes := x.Bytes()
+	copy(word[wordSize-len(bytes):], bytes)
+	return word
+}
+
+func encodeInt(x *big.Int) ([]byte, error) {
+	if x.Cmp(maxI256) > 0 || x.Cmp(minI256) < 0 {
+		return nil, fmt.Errorf("unsupported value: %v overflows int256", x)
+	}
+	if x.Sign() >= 0 {
+		return encodeUint(x), nil
+	}
+	// Two's complement representation of negative numbers.
+	return encodeUint(new(big.Int).Add(maxU256, new(big.Int).Add(x, big.NewInt(1)))), nil
+}
+
+func encodeBytes(bytes []byte) []byte {
+	padded := make([]byte, (len(bytes)+wordSize-1)/wordSize*wordSize)
+	copy(padded, bytes)
+	return append(encodeUint(big.NewInt(int64(len(bytes)))), padded...)
+}
+
+func decodeTuple(data []byte, vals []reflect.Value) error {
+	pos := 0
+	for _, val := range vals {
+		dynamic, err := isDynamic(val.Type())
+		if err != nil {
+			return err
+		}
+		size, err := headSize(val.Type())
+		if err != nil {
+			return err
+		}
+		if len(data) < pos+size {
+			return fmt.Errorf("unexpected end of data: expected at least %v bytes, got %v bytes", pos+size, len(data))
+		}
+		if dynamic {
+			offset, err := decodeLength(data[pos:pos+wordSize], len(data))
+			if err != nil {
+				return fmt.Errorf("decoding offset: %v", err)
+			}
+			if err := decodeValue(data[offset:], val); err != nil {
+				return err
+			}
+		} else {
+			if err := decodeValue(data[pos:pos+size], val); err != nil {
+				return err
+			}
+		}
+		pos += size
+	}
+	return nil
+}
+
+func decodeValue(data []byte, val reflect.Value) error {
+	ty := val.Type()
+	switch ty {
+	case typeString, typeBytes, typeRaw:
+		if len(data) < wordSize {
+			return fmt.Errorf("unexpected end of data: expected at least %v bytes, got %v bytes", wordSize, len(data))
+		}
+		n, err := decodeLength(data[:wordSize], len(data)-wordSize)
+		if err != nil {
+			return fmt.Errorf("decoding length: %v", err)
+		}
+		bytes := make([]byte, n)
+		copy(bytes, data[wordSize:wordSize+n])
+		if ty == typeString {
+			val.SetString(string(bytes))
+		} else {
+			val.SetBytes(bytes)
+		}
+		return nil
+	}
+
+	switch ty.Kind() {
+	case reflect.Array:
+		if ty != typeBytes32 && ty != typeAddress {
+			return decodeTuple(data, elems(val))
+		}
+	case reflect.Slice:
+		if len(data) < wordSize {
+			return fmt.Errorf("unexpected end of data: expected at least %v bytes, got %v bytes", wordSize, len(data))
+		}
+		// Each element takes at least one word, so the length is bounded by the
+		// remaining data.
+		n, err := decodeLength(data[:wordSize], (len(data)-wordSize)/wordSize)
+		if err != nil {
+			return fmt.Errorf("decoding length: %v", err)
+		}
+		slice := reflect.MakeSlice(ty, n, n)
+		if err := decodeTuple(data[wordSize:], elems(slice)); err != nil {
+			return err
+		}
+		val.Set(slice)
+		return nil
+	case reflect.Struct:
+		if ty != typeU128 && ty != typeU256 {
+			return decodeTuple(data, fields(val))
+		}
+	}
+
+	if len(data) < wordSize {
+		return fmt.Errorf("unexpected end of data: expected at least %v bytes, got %v bytes", wordSize, len(data))
+	}
+	word := data[:wordSize]
+	switch ty {
+	case typeBool:
+		x := new(big.Int).SetBytes(word)
+		if x.Cmp(big.NewInt(1)) > 0 {
+			return fmt.Errorf("bad bool: %v", x)
+		}
+		val.SetBool(x.Sign() == 1)
+		return nil
+	case typeBytes32:
+		bytes32 := pack.Bytes32{}
+		copy(bytes32[:], word)
+		val.Set(reflect.ValueOf(bytes32))
+		return nil
+	case typeU8, typeU16, typeU32, typeU64:
+		x := new(big.Int).SetBytes(word)
+		if x.BitLen() > ty.Bits() {
+			return fmt.Errorf("bad uint: %v overflows %v", x, ty)
+		}
+		val.SetUint(x.Uint64())
+		return nil
+	case typeU128:
+		x := new(big.Int).SetBytes(word)
+		if x.BitLen() > 128 {
+			return fmt.Errorf("bad uint: %v overflows %v", x, ty)
+		}
+		val.Set(reflect.ValueOf(pack.NewU128FromInt(x)))
+		return nil
+	case typeU256:
+		val.Set(reflect.ValueOf(pack.NewU256FromInt(new(big.Int).SetBytes(word))))
+		return nil
+	case typeAddress:
+		for _, b := range word[:wordSize-len(Address{})] {
+			if b != 0 {
+				return fmt.Errorf("bad address: %x has dirty upper bytes", word)
+			}
+		}
+		addr := Address{}
+		copy(addr[:], word[wordSize-len(addr):])
+		val.Set(reflect.ValueOf(addr))
+		return nil
+	case typeBigInt:
+		val.Set(reflect.ValueOf(decodeInt(word)))
+		return nil
+	}
+
+	switch ty.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x := decodeInt(word)
+		if !x.IsInt64() || val.OverflowInt(x.Int64()) {
+			return fmt.Errorf("bad int: %v overflows %v", x, ty)
+		}
+		val.SetInt(x.Int64())
+		return nil
+	}
+	return fmt.Errorf("unsupported type %v", ty)
+}
+
+// decodeLength decodes a word as a length, or an offset, that must not exceed
+// the given maximum.
+func decodeLength(word []byte, max int) (int, error) {
+	x := new(big.Int).SetBytes(word)
+	if !x.IsInt64() || x.Int64() > int64(max) {
+		return 0, fmt.Errorf("%v exceeds %v", x, max)
+	}
+	return int(x.Int64()), nil
+}
+
+func decodeInt(word []byte) *big.Int {
+	x := new(big.Int).SetBytes(word)
+	if x.Cmp(maxI256) > 0 {
+		// Two's complement representation of negative numbers.
+		x.Sub(x, new(big.Int).Add(maxU256, big.NewInt(1)))
 	}
-	return packed
+	return x
 }
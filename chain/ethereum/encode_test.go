@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
+	"math/big"
 	"testing/quick"
 
 	"github.com/renproject/multichain/chain/ethereum"
@@ -20,7 +21,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x []byte) bool {
 				arg := pack.NewBytes(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 
 				expectedBytes := make([]byte, int(math.Ceil(float64(len(x))/32)*32))
@@ -43,7 +45,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x [32]byte) bool {
 				arg := pack.NewBytes32(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := hex.EncodeToString(x[:])
 
@@ -61,7 +64,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x uint8) bool {
 				arg := pack.NewU8(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := fmt.Sprintf("%064x", x)
 
@@ -79,7 +83,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x uint16) bool {
 				arg := pack.NewU16(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := fmt.Sprintf("%064x", x)
 
@@ -97,7 +102,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x uint32) bool {
 				arg := pack.NewU32(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := fmt.Sprintf("%064x", x)
 
@@ -115,7 +121,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x uint64) bool {
 				arg := pack.NewU64(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := fmt.Sprintf("%064x", x)
 
@@ -133,7 +140,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x [16]byte) bool {
 				arg := pack.NewU128(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := fmt.Sprintf("%064x", x)
 
@@ -151,7 +159,8 @@ var _ = Describe("Encoding", func() {
 			f := func(x [32]byte) bool {
 				arg := pack.NewU256(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 				expectedString := fmt.Sprintf("%064x", x)
 
@@ -169,11 +178,12 @@ var _ = Describe("Encoding", func() {
 			f := func(x [20]byte) bool {
 				arg := ethereum.Address(x)
 
-				resBytes := ethereum.Encode(arg)
+				resBytes, err := ethereum.Encode(arg)
+				Expect(err).ToNot(HaveOccurred())
 				resString := hex.EncodeToString(resBytes)
 
 				expectedBytes := make([]byte, 32)
-				copy(expectedBytes, x[:])
+				copy(expectedBytes[12:], x[:])
 				expectedString := hex.EncodeToString(expectedBytes)
 
 				Expect(resString).To(Equal(expectedString))
@@ -186,10 +196,10 @@ var _ = Describe("Encoding", func() {
 	})
 
 	Context("when encoding an unsupported type", func() {
-		It("should panic", func() {
-			f := func(x bool) bool {
-				arg := pack.NewBool(x)
-				Expect(func() { ethereum.Encode(arg) }).To(Panic())
+		It("should return an error", func() {
+			f := func(x float64) bool {
+				_, err := ethereum.Encode(x)
+				Expect(err).To(HaveOccurred())
 				return true
 			}
 
@@ -210,13 +220,13 @@ var _ = Describe("Encoding", func() {
 			addr:   "797522Fb74d42bB9fbF6b76dEa24D01A538d5D66",
 			amount: 10000,
 			hash:   "702826c3977ee72158db2ce1fb758075ee2799db65fb27b5d0952f860a8084ed",
-			result: "797522fb74d42bb9fbf6b76dea24d01a538d5d660000000000000000000000000000000000000000000000000000000000000000000000000000000000002710702826c3977ee72158db2ce1fb758075ee2799db65fb27b5d0952f860a8084ed",
+			result: "000000000000000000000000797522fb74d42bb9fbf6b76dea24d01a538d5d660000000000000000000000000000000000000000000000000000000000002710702826c3977ee72158db2ce1fb758075ee2799db65fb27b5d0952f860a8084ed",
 		},
 		{
 			addr:   "58afb504ef2444a267b8c7ce57279417f1377ceb",
 			amount: 50000000000000000,
 			hash:   "dabff9ceb1b3dabb696d143326fdb98a8c7deb260e65d08a294b16659d573f93",
-			result: "58afb504ef2444a267b8c7ce57279417f1377ceb00000000000000000000000000000000000000000000000000000000000000000000000000b1a2bc2ec50000dabff9ceb1b3dabb696d143326fdb98a8c7deb260e65d08a294b16659d573f93",
+			result: "00000000000000000000000058afb504ef2444a267b8c7ce57279417f1377ceb00000000000000000000000000000000000000000000000000b1a2bc2ec50000dabff9ceb1b3dabb696d143326fdb98a8c7deb260e65d08a294b16659d573f93",
 		},
 		{
 			addr:   "0000000000000000000000000000000000000000",
@@ -244,7 +254,8 @@ var _ = Describe("Encoding", func() {
 				pack.NewU64(test.amount),
 				pack.NewBytes32(hashBytes32),
 			}
-			result := ethereum.Encode(args...)
+			result, err := ethereum.Encode(args...)
+			Expect(err).ToNot(HaveOccurred())
 			Expect(hex.EncodeToString(result)).To(Equal(test.result))
 		},
 
@@ -253,3 +264,100 @@ var _ = Describe("Encoding", func() {
 		Entry("should return the same result as solidity for empty transactions", testCases[2]),
 	)
 })
+
+var _ = Describe("Decoding", func() {
+	type transfer struct {
+		To     ethereum.Address
+		Amount pack.U256
+		Memo   pack.String
+	}
+
+	Context("when decoding values that were encoded", func() {
+		It("should return the original values", func() {
+			f := func(b bool, s string, i int64, data []byte, arr [3]uint16, addr [20]byte) bool {
+				inBool, inString, inInt, inBytes := pack.NewBool(b), pack.String(s), i, pack.NewBytes(data)
+				inArr := [3]pack.U16{pack.NewU16(arr[0]), pack.NewU16(arr[1]), pack.NewU16(arr[2])}
+				inList := []pack.U64{pack.NewU64(uint64(i)), pack.NewU64(0)}
+				inTuple := transfer{To: ethereum.Address(addr), Amount: pack.NewU256FromU64(pack.NewU64(uint64(i))), Memo: pack.String(s)}
+
+				data, err := ethereum.Encode(inBool, inString, inInt, inBytes, inArr, inList, inTuple)
+				Expect(err).ToNot(HaveOccurred())
+
+				var outBool pack.Bool
+				var outString pack.String
+				var outInt int64
+				var outBytes pack.Bytes
+				var outArr [3]pack.U16
+				var outList []pack.U64
+				var outTuple transfer
+				Expect(ethereum.Decode(data, &outBool, &outString, &outInt, &outBytes, &outArr, &outList, &outTuple)).To(Succeed())
+
+				Expect(outBool).To(Equal(inBool))
+				Expect(outString).To(Equal(inString))
+				Expect(outInt).To(Equal(inInt))
+				Expect([]byte(outBytes)).To(Equal([]byte(inBytes)))
+				Expect(outArr).To(Equal(inArr))
+				Expect(outList).To(Equal(inList))
+				Expect(outTuple.To).To(Equal(inTuple.To))
+				Expect(outTuple.Amount.Equal(inTuple.Amount)).To(BeTrue())
+				Expect(outTuple.Memo).To(Equal(inTuple.Memo))
+				return true
+			}
+
+			err := quick.Check(f, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when decoding values encoded by solidity", func() {
+		It("should return the correct result", func() {
+			// abi.encode(int256(-1), true, "hi")
+			data, err := hex.DecodeString(
+				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" +
+					"0000000000000000000000000000000000000000000000000000000000000001" +
+					"0000000000000000000000000000000000000000000000000000000000000060" +
+					"0000000000000000000000000000000000000000000000000000000000000002" +
+					"6869000000000000000000000000000000000000000000000000000000000000")
+			Expect(err).ToNot(HaveOccurred())
+
+			x := new(big.Int)
+			var b pack.Bool
+			var s pack.String
+			Expect(ethereum.Decode(data, &x, &b, &s)).To(Succeed())
+			Expect(x.Int64()).To(Equal(int64(-1)))
+			Expect(b).To(Equal(pack.NewBool(true)))
+			Expect(s).To(Equal(pack.String("hi")))
+
+			reencoded, err := ethereum.Encode(x, b, s)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reencoded).To(Equal(data))
+		})
+	})
+
+	Context("when decoding a value that overflows its type", func() {
+		It("should return an error", func() {
+			data, err := ethereum.Encode(pack.NewU64(256))
+			Expect(err).ToNot(HaveOccurred())
+			var x pack.U8
+			Expect(ethereum.Decode(data, &x)).ToNot(Succeed())
+		})
+	})
+
+	Context("when decoding truncated data", func() {
+		It("should return an error", func() {
+			data, err := ethereum.Encode(pack.String("hello"), pack.NewU64(1))
+			Expect(err).ToNot(HaveOccurred())
+			var s pack.String
+			var x pack.U64
+			Expect(ethereum.Decode(data[:len(data)-32], &s, &x)).ToNot(Succeed())
+		})
+	})
+
+	Context("when decoding into a non-pointer", func() {
+		It("should return an error", func() {
+			data, err := ethereum.Encode(pack.NewU64(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ethereum.Decode(data, pack.NewU64(0))).ToNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,259 @@
+// Package erc20 implements helpers for interacting with ERC-20 token contracts
+// on Ethereum-compatible chains. Transactions are built using an
+// account.TxBuilder (usually an ethereum.TxBuilder), and readonly functions are
+// called using a contract.Caller (usually an ethereum.Client).
+package erc20
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+)
+
+// Function selectors and event topics defined by the ERC-20 standard.
+var (
+	TransferSelector     = selector("transfer(address,uint256)")
+	ApproveSelector      = selector("approve(address,uint256)")
+	TransferFromSelector = selector("transferFrom(address,address,uint256)")
+	BalanceOfSelector    = selector("balanceOf(address)")
+	AllowanceSelector    = selector("allowance(address,address)")
+	DecimalsSelector     = selector("decimals()")
+
+	TransferTopic = pack.NewBytes32(crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")))
+)
+
+// selector returns the first 4 bytes of the Keccak256 hash of the function
+// signature.
+func selector(signature string) [4]byte {
+	sel := [4]byte{}
+	copy(sel[:], crypto.Keccak256([]byte(signature)))
+	return sel
+}
+
+// encodeCall returns the calldata that calls the function with the given
+// selector, using the given arguments.
+func encodeCall(sel [4]byte, args ...interface{}) (pack.Bytes, error) {
+	data, err := ethereum.Encode(args...)
+	if err != nil {
+		return nil, err
+	}
+	return pack.NewBytes(append(sel[:], data...)), nil
+}
+
+// TransferPayload returns the calldata that transfers the given amount of
+// tokens from the sender to the recipient.
+func TransferPayload(to address.Address, amount pack.U256) (pack.Bytes, error) {
+	toAddr, err := ethereum.NewAddressFromHex(string(to))
+	if err != nil {
+		return nil, fmt.Errorf("bad to address: %v", err)
+	}
+	return encodeCall(TransferSelector, toAddr, amount)
+}
+
+// ApprovePayload returns the calldata that allows the spender to transfer up
+// to the given amount of tokens from the sender.
+func ApprovePayload(spender address.Address, amount pack.U256) (pack.Bytes, error) {
+	spenderAddr, err := ethereum.NewAddressFromHex(string(spender))
+	if err != nil {
+		return nil, fmt.Errorf("bad spender address: %v", err)
+	}
+	return encodeCall(ApproveSelector, spenderAddr, amount)
+}
+
+// TransferFromPayload returns the calldata that transfers the given amount of
+// tokens from one address to another, using the allowance given to the sender.
+func TransferFromPayload(from, to address.Address, amount pack.U256) (pack.Bytes, error) {
+	fromAddr, err := ethereum.NewAddressFromHex(string(from))
+	if err != nil {
+		return nil, fmt.Errorf("bad from address: %v", err)
+	}
+	toAddr, err := ethereum.NewAddressFromHex(string(to))
+	if err != nil {
+		return nil, fmt.Errorf("bad to address: %v", err)
+	}
+	return encodeCall(TransferFromSelector, fromAddr, toAddr, amount)
+}
+
+// The TxBuilder builds transactions that call the functions of an ERC-20 token
+// contract. No ether is sent with the transactions.
+type TxBuilder struct {
+	txBuilder account.TxBuilder
+	token     address.Address
+}
+
+// NewTxBuilder returns a transaction builder that uses the given account
+// transaction builder to build transactions to the given token contract.
+func NewTxBuilder(txBuilder account.TxBuilder, token address.Address) TxBuilder {
+	return TxBuilder{
+		txBuilder: txBuilder,
+		token:     token,
+	}
+}
+
+// BuildTransferTx returns an unsigned transaction that transfers the given
+// amount of tokens from one address to another.
+func (txBuilder TxBuilder) BuildTransferTx(from, to address.Address, amount, nonce pack.U256) (account.Tx, error) {
+	payload, err := TransferPayload(to, amount)
+	if err != nil {
+		return nil, err
+	}
+	return txBuilder.txBuilder.BuildTx(from, txBuilder.token, pack.NewU256FromU64(pack.NewU64(0)), nonce, payload)
+}
+
+// BuildApproveTx returns an unsigned transaction that allows the spender to
+// transfer up to the given amount of tokens from the owner.
+func (txBuilder TxBuilder) BuildApproveTx(owner, spender address.Address, amount, nonce pack.U256) (account.Tx, error) {
+	payload, err := ApprovePayload(spender, amount)
+	if err != nil {
+		return nil, err
+	}
+	return txBuilder.txBuilder.BuildTx(owner, txBuilder.token, pack.NewU256FromU64(pack.NewU64(0)), nonce, payload)
+}
+
+// BuildTransferFromTx returns an unsigned transaction, sent by the spender,
+// that transfers the given amount of tokens from one address to another.
+func (txBuilder TxBuilder) BuildTransferFromTx(spender, from, to address.Address, amount, nonce pack.U256) (account.Tx, error) {
+	payload, err := TransferFromPayload(from, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	return txBuilder.txBuilder.BuildTx(spender, txBuilder.token, pack.NewU256FromU64(pack.NewU64(0)), nonce, payload)
+}
+
+// The Caller calls the readonly functions of an ERC-20 token contract.
+type Caller struct {
+	caller contract.Caller
+	token  address.Address
+}
+
+// NewCaller returns a Caller that uses the given contract caller to call the
+// given token contract.
+func NewCaller(caller contract.Caller, token address.Address) Caller {
+	return Caller{
+		caller: caller,
+		token:  token,
+	}
+}
+
+// BalanceOf returns the balance of the owner, in the smallest unit of the
+// token.
+func (caller Caller) BalanceOf(ctx context.Context, owner address.Address) (pack.U256, error) {
+	ownerAddr, err := ethereum.NewAddressFromHex(string(owner))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad owner address: %v", err)
+	}
+	balance := pack.U256{}
+	if err := caller.call(ctx, &balance, BalanceOfSelector, ownerAddr); err != nil {
+		return pack.U256{}, fmt.Errorf("bad balanceOf: %v", err)
+	}
+	return balance, nil
+}
+
+// Allowance returns the amount of tokens that the spender is allowed to
+// transfer from the owner.
+func (caller Caller) Allowance(ctx context.Context, owner, spender address.Address) (pack.U256, error) {
+	ownerAddr, err := ethereum.NewAddressFromHex(string(owner))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad owner address: %v", err)
+	}
+	spenderAddr, err := ethereum.NewAddressFromHex(string(spender))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad spender address: %v", err)
+	}
+	allowance := pack.U256{}
+	if err := caller.call(ctx, &allowance, AllowanceSelector, ownerAddr, spenderAddr); err != nil {
+		return pack.U256{}, fmt.Errorf("bad allowance: %v", err)
+	}
+	return allowance, nil
+}
+
+// Decimals returns the number of decimals used to display amounts of the token.
+// For example, a token with 18 decimals displays an amount of 10^18 as 1.
+func (caller Caller) Decimals(ctx context.Context) (pack.U8, error) {
+	decimals := pack.U8(0)
+	if err := caller.call(ctx, &decimals, DecimalsSelector); err != nil {
+		return pack.U8(0), fmt.Errorf("bad decimals: %v", err)
+	}
+	return decimals, nil
+}
+
+// call the function with the given selector and arguments, and decode the
+// output into the result.
+func (caller Caller) call(ctx context.Context, result interface{}, sel [4]byte, args ...interface{}) error {
+	calldata, err := encodeCall(sel, args...)
+	if err != nil {
+		return err
+	}
+	output, err := caller.caller.CallContract(ctx, caller.token, contract.CallData(calldata))
+	if err != nil {
+		return err
+	}
+	return ethereum.Decode(output, result)
+}
+
+// A Transfer event is emitted by the token contract whenever tokens are
+// transferred (including transfers from the zero address when tokens are
+// minted, and to the zero address when tokens are burned).
+type Transfer struct {
+	Token address.Address
+	From  address.Address
+	To    address.Address
+	Value pack.U256
+}
+
+// DecodeTransfer decodes a Transfer event from a log. An error is returned if
+// the log is not a Transfer event.
+func DecodeTransfer(log ethereum.Log) (Transfer, error) {
+	if len(log.Topics) != 3 || log.Topics[0] != TransferTopic {
+		return Transfer{}, fmt.Errorf("bad log: expected Transfer event")
+	}
+	from, to := ethereum.Address{}, ethereum.Address{}
+	if err := ethereum.Decode(log.Topics[1][:], &from); err != nil {
+		return Transfer{}, fmt.Errorf("bad from address: %v", err)
+	}
+	if err := ethereum.Decode(log.Topics[2][:], &to); err != nil {
+		return Transfer{}, fmt.Errorf("bad to address: %v", err)
+	}
+	value := pack.U256{}
+	if err := ethereum.Decode(log.Data, &value); err != nil {
+		return Transfer{}, fmt.Errorf("bad value: %v", err)
+	}
+	return Transfer{
+		Token: log.Address,
+		From:  address.Address(from.String()),
+		To:    address.Address(to.String()),
+		Value: value,
+	}, nil
+}
+
+// DecodeTransfers decodes all of the Transfer events emitted by the given token
+// contract, ignoring all other logs. Usually, the logs are returned by
+// ethereum.Client.Logs.
+func DecodeTransfers(token address.Address, logs []ethereum.Log) ([]Transfer, error) {
+	tokenAddr, err := ethereum.NewAddressFromHex(string(token))
+	if err != nil {
+		return nil, fmt.Errorf("bad token address: %v", err)
+	}
+	transfers := []Transfer{}
+	for _, log := range logs {
+		logAddr, err := ethereum.NewAddressFromHex(string(log.Address))
+		if err != nil || logAddr != tokenAddr {
+			continue
+		}
+		if len(log.Topics) == 0 || log.Topics[0] != TransferTopic {
+			continue
+		}
+		transfer, err := DecodeTransfer(log)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
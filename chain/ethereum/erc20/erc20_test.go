@@ -0,0 +1,181 @@
+package erc20_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/multichain/chain/ethereum/erc20"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A callerFunc implements the contract.Caller interface using a function.
+type callerFunc func(address.Address, contract.CallData) (pack.Bytes, error)
+
+func (f callerFunc) CallContract(ctx context.Context, addr address.Address, calldata contract.CallData) (pack.Bytes, error) {
+	return f(addr, calldata)
+}
+
+// topic returns the ABI encoding of a value as an indexed topic.
+func topic(val interface{}) pack.Bytes32 {
+	t := pack.Bytes32{}
+	copy(t[:], word(val))
+	return t
+}
+
+// word returns the ABI encoding of a value as a single word.
+func word(val interface{}) []byte {
+	data, err := ethereum.Encode(val)
+	Expect(err).ToNot(HaveOccurred())
+	return data
+}
+
+var _ = Describe("ERC20", func() {
+	token := address.Address("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	alice := address.Address("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	bob := address.Address("0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359")
+	amount := pack.NewU256FromU64(pack.NewU64(1000))
+
+	Context("when building payloads", func() {
+		It("should encode a transfer", func() {
+			payload, err := erc20.TransferPayload(bob, amount)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hex.EncodeToString(payload[:4])).To(Equal("a9059cbb"))
+			Expect(payload).To(HaveLen(4 + 2*32))
+
+			to, value := ethereum.Address{}, pack.U256{}
+			Expect(ethereum.Decode(payload[4:], &to, &value)).To(Succeed())
+			Expect(address.Address(to.String())).To(Equal(bob))
+			Expect(value).To(Equal(amount))
+		})
+
+		It("should encode an approval", func() {
+			payload, err := erc20.ApprovePayload(bob, amount)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hex.EncodeToString(payload[:4])).To(Equal("095ea7b3"))
+			Expect(payload).To(HaveLen(4 + 2*32))
+		})
+
+		It("should encode a transfer from an allowance", func() {
+			payload, err := erc20.TransferFromPayload(alice, bob, amount)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hex.EncodeToString(payload[:4])).To(Equal("23b872dd"))
+
+			from, to, value := ethereum.Address{}, ethereum.Address{}, pack.U256{}
+			Expect(ethereum.Decode(payload[4:], &from, &to, &value)).To(Succeed())
+			Expect(address.Address(from.String())).To(Equal(alice))
+			Expect(address.Address(to.String())).To(Equal(bob))
+			Expect(value).To(Equal(amount))
+		})
+
+		It("should return an error for a bad address", func() {
+			_, err := erc20.TransferPayload(address.Address("0x1234"), amount)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building transactions", func() {
+		It("should send the payload to the token contract", func() {
+			txBuilder := erc20.NewTxBuilder(ethereum.NewTxBuilder(pack.NewU256FromU64(pack.NewU64(1)), pack.NewU64(100000), pack.NewU256FromU64(pack.NewU64(1))), token)
+			tx, err := txBuilder.BuildTransferTx(alice, bob, amount, pack.NewU256FromU64(pack.NewU64(3)))
+			Expect(err).ToNot(HaveOccurred())
+
+			payload, err := erc20.TransferPayload(bob, amount)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.From()).To(Equal(alice))
+			Expect(tx.To()).To(Equal(token))
+			Expect(tx.Value().Int().Sign()).To(Equal(0))
+			Expect(tx.Nonce()).To(Equal(pack.NewU256FromU64(pack.NewU64(3))))
+			Expect(tx.Payload()).To(Equal(contract.CallData(payload)))
+		})
+	})
+
+	Context("when calling readonly functions", func() {
+		caller := erc20.NewCaller(callerFunc(func(addr address.Address, calldata contract.CallData) (pack.Bytes, error) {
+			Expect(addr).To(Equal(token))
+			switch hex.EncodeToString(calldata[:4]) {
+			case "70a08231": // balanceOf(address)
+				return pack.NewBytes(word(amount)), nil
+			case "dd62ed3e": // allowance(address,address)
+				Expect(calldata).To(HaveLen(4 + 2*32))
+				return pack.NewBytes(word(pack.NewU256FromU64(pack.NewU64(42)))), nil
+			case "313ce567": // decimals()
+				Expect(calldata).To(HaveLen(4))
+				return pack.NewBytes(word(pack.U8(18))), nil
+			default:
+				return nil, fmt.Errorf("unexpected call")
+			}
+		}), token)
+
+		It("should return the balance", func() {
+			balance, err := caller.BalanceOf(context.Background(), alice)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(amount))
+		})
+
+		It("should return the allowance", func() {
+			allowance, err := caller.Allowance(context.Background(), alice, bob)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allowance).To(Equal(pack.NewU256FromU64(pack.NewU64(42))))
+		})
+
+		It("should return the decimals", func() {
+			decimals, err := caller.Decimals(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decimals).To(Equal(pack.U8(18)))
+		})
+
+		It("should return an error when the decimals are out of range", func() {
+			caller := erc20.NewCaller(callerFunc(func(address.Address, contract.CallData) (pack.Bytes, error) {
+				return pack.NewBytes(word(pack.NewU256FromU64(pack.NewU64(256)))), nil
+			}), token)
+			_, err := caller.Decimals(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when decoding logs", func() {
+		transferLog := func(emitter address.Address, from, to address.Address, value pack.U256) ethereum.Log {
+			fromAddr, err := ethereum.NewAddressFromHex(string(from))
+			Expect(err).ToNot(HaveOccurred())
+			toAddr, err := ethereum.NewAddressFromHex(string(to))
+			Expect(err).ToNot(HaveOccurred())
+			return ethereum.Log{
+				Address: emitter,
+				Topics: []pack.Bytes32{
+					erc20.TransferTopic,
+					topic(fromAddr),
+					topic(toAddr),
+				},
+				Data: pack.NewBytes(word(value)),
+			}
+		}
+
+		It("should only decode transfers emitted by the token", func() {
+			other := address.Address("0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB")
+			logs := []ethereum.Log{
+				transferLog(token, alice, bob, amount),
+				transferLog(other, alice, bob, amount),
+				{Address: token, Topics: []pack.Bytes32{{0x01}}},
+				transferLog(token, bob, alice, pack.NewU256FromU64(pack.NewU64(1))),
+			}
+			transfers, err := erc20.DecodeTransfers(token, logs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transfers).To(Equal([]erc20.Transfer{
+				{Token: token, From: alice, To: bob, Value: amount},
+				{Token: token, From: bob, To: alice, Value: pack.NewU256FromU64(pack.NewU64(1))},
+			}))
+		})
+
+		It("should return an error for other events", func() {
+			_, err := erc20.DecodeTransfer(ethereum.Log{Address: token, Topics: []pack.Bytes32{{0x01}}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -1 +1,350 @@
 package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultClientTimeout used by the Client.
+	DefaultClientTimeout = time.Minute
+	// DefaultClientHost used by the Client. This should only be used for local
+	// deployments of the multichain.
+	DefaultClientHost = "http://127.0.0.1:8545"
+	// DefaultClientLogPageSize is the number of blocks that are queried in each
+	// "eth_getLogs" request. Most nodes limit the range of blocks, or number of
+	// logs, that can be returned by a single request.
+	DefaultClientLogPageSize = 2000
+	// DefaultClientPollInterval used by the Client when waiting for new
+	// blocks.
+	DefaultClientPollInterval = 15 * time.Second
+)
+
+// ClientOptions are used to parameterise the behaviour of the Client.
+type ClientOptions struct {
+	Timeout      time.Duration
+	Host         string
+	LogPageSize  uint64
+	PollInterval time.Duration
+}
+
+// DefaultClientOptions returns ClientOptions with the default settings. These
+// settings are valid for use with the default local deployment of the
+// multichain. In production, the host should be changed.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:      DefaultClientTimeout,
+		Host:         DefaultClientHost,
+		LogPageSize:  DefaultClientLogPageSize,
+		PollInterval: DefaultClientPollInterval,
+	}
+}
+
+// WithHost sets the URL of the Ethereum node.
+func (opts ClientOptions) WithHost(host string) ClientOptions {
+	opts.Host = host
+	return opts
+}
+
+// WithTimeout sets the timeout used for each request made to the Ethereum
+// node.
+func (opts ClientOptions) WithTimeout(timeout time.Duration) ClientOptions {
+	opts.Timeout = timeout
+	return opts
+}
+
+// WithLogPageSize sets the maximum number of blocks that are queried in each
+// "eth_getLogs" request. Large block ranges are split into pages of this size.
+func (opts ClientOptions) WithLogPageSize(pageSize uint64) ClientOptions {
+	opts.LogPageSize = pageSize
+	return opts
+}
+
+// WithPollInterval sets the interval at which the Ethereum node is polled for
+// new blocks when streaming logs.
+func (opts ClientOptions) WithPollInterval(interval time.Duration) ClientOptions {
+	opts.PollInterval = interval
+	return opts
+}
+
+// A Client interacts with an instance of the Ethereum network using the
+// JSON-RPC interface exposed by an Ethereum node. It implements the Account API
+// and the Contract API, and can be used with any Ethereum-compatible chain
+// that exposes the standard "eth_" namespace.
+type Client struct {
+	opts      ClientOptions
+	rpcClient *rpc.Client
+}
+
+// NewClient returns a new Client. No connection is made to the Ethereum node
+// until the first request.
+func NewClient(opts ClientOptions) (*Client, error) {
+	httpClient := &http.Client{}
+	httpClient.Timeout = opts.Timeout
+	rpcClient, err := rpc.DialHTTPWithClient(opts.Host, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %v: %v", opts.Host, err)
+	}
+	return &Client{
+		opts:      opts,
+		rpcClient: rpcClient,
+	}, nil
+}
+
+// Tx returns the transaction uniquely identified by the given transaction
+// hash, and its number of confirmations. Pending transactions have zero
+// confirmations. Transactions that have been mined, but were reverted, are
+// treated as invalid and an error is returned.
+func (client *Client) Tx(ctx context.Context, txHash pack.Bytes) (account.Tx, pack.U64, error) {
+	hash := common.BytesToHash(txHash)
+
+	raw := json.RawMessage{}
+	if err := client.rpcClient.CallContext(ctx, &raw, "eth_getTransactionByHash", hash); err != nil {
+		return nil, pack.NewU64(0), fmt.Errorf("bad \"eth_getTransactionByHash\": %v", err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, pack.NewU64(0), fmt.Errorf("bad \"eth_getTransactionByHash\": %v not found", hash.Hex())
+	}
+	tx, err := decodeTx(raw)
+	if err != nil {
+		return nil, pack.NewU64(0), fmt.Errorf("bad tx: %v", err)
+	}
+	meta := struct {
+		BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+	}{}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, pack.NewU64(0), fmt.Errorf("bad block number: %v", err)
+	}
+	if meta.BlockNumber == nil {
+		// The transaction has not been mined.
+		return tx, pack.NewU64(0), nil
+	}
+
+	receipt := (*struct {
+		Status *hexutil.Uint64 `json:"status"`
+	})(nil)
+	if err := client.rpcClient.CallContext(ctx, &receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, pack.NewU64(0), fmt.Errorf("bad \"eth_getTransactionReceipt\": %v", err)
+	}
+	if receipt == nil {
+		// The node knows the block of the transaction, but has not indexed
+		// its receipt yet.
+		return tx, pack.NewU64(0), nil
+	}
+	if receipt.Status == nil {
+		return nil, pack.NewU64(0), fmt.Errorf("bad receipt: expected status")
+	}
+	if *receipt.Status == hexutil.Uint64(types.ReceiptStatusFailed) {
+		return nil, pack.NewU64(0), fmt.Errorf("bad tx: %v reverted", hash.Hex())
+	}
+
+	head := hexutil.Uint64(0)
+	if err := client.rpcClient.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		return nil, pack.NewU64(0), fmt.Errorf("bad \"eth_blockNumber\": %v", err)
+	}
+	if head < *meta.BlockNumber {
+		// The node has not caught up to its own view of the transaction.
+		return tx, pack.NewU64(0), nil
+	}
+	return tx, pack.NewU64(uint64(head-*meta.BlockNumber) + 1), nil
+}
+
+// SubmitTx to the Ethereum network. The transaction must be signed.
+func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
+	serial, err := tx.Serialize()
+	if err != nil {
+		return fmt.Errorf("bad tx: %v", err)
+	}
+	txHash := common.Hash{}
+	if err := client.rpcClient.CallContext(ctx, &txHash, "eth_sendRawTransaction", hexutil.Bytes(serial)); err != nil {
+		return fmt.Errorf("bad \"eth_sendRawTransaction\": %v", err)
+	}
+	return nil
+}
+
+// AccountNonce returns the nonce that should be used by the next transaction
+// from the given address, including transactions that are still pending.
+func (client *Client) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	from, err := NewAddressFromHex(string(addr))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad address: %v", err)
+	}
+	nonce := hexutil.Uint64(0)
+	if err := client.rpcClient.CallContext(ctx, &nonce, "eth_getTransactionCount", common.Address(from), "pending"); err != nil {
+		return pack.U256{}, fmt.Errorf("bad \"eth_getTransactionCount\": %v", err)
+	}
+	return pack.NewU256FromU64(pack.NewU64(uint64(nonce))), nil
+}
+
+// AccountBalance returns the balance of the given address, in wei, as of the
+// latest block.
+func (client *Client) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	from, err := NewAddressFromHex(string(addr))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad address: %v", err)
+	}
+	balance := hexutil.Big{}
+	if err := client.rpcClient.CallContext(ctx, &balance, "eth_getBalance", common.Address(from), "latest"); err != nil {
+		return pack.U256{}, fmt.Errorf("bad \"eth_getBalance\": %v", err)
+	}
+	return pack.NewU256FromInt(balance.ToInt()), nil
+}
+
+// CallContract at the specified address, using the specified calldata as
+// input. The call is executed against the latest block, and does not mutate
+// any state. Use CallContractAt (or CallContractAtBlock) to execute the call
+// against a different block.
+func (client *Client) CallContract(ctx context.Context, contractAddr address.Address, calldata contract.CallData) (pack.Bytes, error) {
+	return client.CallContractAtBlock(ctx, contractAddr, calldata, LatestBlock)
+}
+
+// FeeHistory of the most recent blocks, as returned by "eth_feeHistory".
+type FeeHistory struct {
+	// OldestBlock is the number of the first block in the history.
+	OldestBlock pack.U64
+	// BaseFeePerGas of each block in the history. There is one more base fee
+	// than there are blocks, because the base fee of the next block (the one
+	// after the newest block in the history) is also included.
+	BaseFeePerGas []pack.U256
+	// GasUsedRatio of each block in the history.
+	GasUsedRatio []float64
+	// Reward paid per gas in each block, at each of the requested percentiles
+	// of the priority fees paid by the transactions in the block.
+	Reward [][]pack.U256
+}
+
+// FeeHistory returns the base fees and priority fees paid in the given number
+// of most recent blocks. Priority fees are sampled at each of the given
+// percentiles.
+func (client *Client) FeeHistory(ctx context.Context, blockCount uint64, rewardPercentiles []float64) (FeeHistory, error) {
+	resp := struct {
+		OldestBlock   hexutil.Uint64   `json:"oldestBlock"`
+		BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+		GasUsedRatio  []float64        `json:"gasUsedRatio"`
+		Reward        [][]*hexutil.Big `json:"reward"`
+	}{}
+	if err := client.rpcClient.CallContext(ctx, &resp, "eth_feeHistory", hexutil.Uint64(blockCount), "latest", rewardPercentiles); err != nil {
+		return FeeHistory{}, fmt.Errorf("bad \"eth_feeHistory\": %v", err)
+	}
+
+	history := FeeHistory{
+		OldestBlock:   pack.NewU64(uint64(resp.OldestBlock)),
+		BaseFeePerGas: make([]pack.U256, len(resp.BaseFeePerGas)),
+		GasUsedRatio:  resp.GasUsedRatio,
+		Reward:        make([][]pack.U256, len(resp.Reward)),
+	}
+	for i, baseFee := range resp.BaseFeePerGas {
+		if baseFee == nil {
+			return FeeHistory{}, fmt.Errorf("bad base fee: %v is nil", i)
+		}
+		history.BaseFeePerGas[i] = pack.NewU256FromInt(baseFee.ToInt())
+	}
+	for i, rewards := range resp.Reward {
+		history.Reward[i] = make([]pack.U256, len(rewards))
+		for j, reward := range rewards {
+			if reward == nil {
+				return FeeHistory{}, fmt.Errorf("bad reward: %v/%v is nil", i, j)
+			}
+			history.Reward[i][j] = pack.NewU256FromInt(reward.ToInt())
+		}
+	}
+	return history, nil
+}
+
+// decodeTx from the web3 RPC transaction format, recovering the sender from the
+// signature. Legacy transactions and EIP-1559 transactions are supported.
+func decodeTx(raw json.RawMessage) (account.Tx, error) {
+	envelope := struct {
+		Type *hexutil.Uint64 `json:"type"`
+	}{}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Type != nil {
+		switch *envelope.Type {
+		case 0:
+		case DynamicFeeTxType:
+			return decodeDynamicFeeTx(raw)
+		default:
+			return nil, fmt.Errorf("unsupported tx type %v", *envelope.Type)
+		}
+	}
+
+	tx := new(types.Transaction)
+	if err := json.Unmarshal(raw, tx); err != nil {
+		return nil, err
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, fmt.Errorf("recovering sender: %v", err)
+	}
+	return &Tx{tx: tx, signer: signer, from: Address(from), signed: true}, nil
+}
+
+// decodeDynamicFeeTx from the web3 RPC transaction format, recovering the
+// sender from the signature.
+func decodeDynamicFeeTx(raw json.RawMessage) (account.Tx, error) {
+	dec := struct {
+		ChainID    *hexutil.Big    `json:"chainId"`
+		Nonce      *hexutil.Uint64 `json:"nonce"`
+		GasTipCap  *hexutil.Big    `json:"maxPriorityFeePerGas"`
+		GasFeeCap  *hexutil.Big    `json:"maxFeePerGas"`
+		Gas        *hexutil.Uint64 `json:"gas"`
+		To         *common.Address `json:"to"`
+		Value      *hexutil.Big    `json:"value"`
+		Input      *hexutil.Bytes  `json:"input"`
+		AccessList []AccessTuple   `json:"accessList"`
+		V          *hexutil.Big    `json:"v"`
+		R          *hexutil.Big    `json:"r"`
+		S          *hexutil.Big    `json:"s"`
+	}{}
+	if err := json.Unmarshal(raw, &dec); err != nil {
+		return nil, err
+	}
+	if dec.ChainID == nil || dec.Nonce == nil || dec.GasTipCap == nil || dec.GasFeeCap == nil || dec.Gas == nil || dec.Value == nil || dec.Input == nil || dec.V == nil || dec.R == nil || dec.S == nil {
+		return nil, fmt.Errorf("missing required field")
+	}
+	if dec.AccessList == nil {
+		dec.AccessList = []AccessTuple{}
+	}
+
+	tx := &DynamicFeeTx{
+		data: dynamicFeeTxData{
+			ChainID:    dec.ChainID.ToInt(),
+			Nonce:      uint64(*dec.Nonce),
+			GasTipCap:  dec.GasTipCap.ToInt(),
+			GasFeeCap:  dec.GasFeeCap.ToInt(),
+			Gas:        uint64(*dec.Gas),
+			To:         dec.To,
+			Value:      dec.Value.ToInt(),
+			Data:       *dec.Input,
+			AccessList: dec.AccessList,
+			V:          dec.V.ToInt(),
+			R:          dec.R.ToInt(),
+			S:          dec.S.ToInt(),
+		},
+		signed: true,
+	}
+	from, err := tx.sender()
+	if err != nil {
+		return nil, fmt.Errorf("recovering sender: %v", err)
+	}
+	tx.from = from
+	return tx, nil
+}
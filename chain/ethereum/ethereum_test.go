@@ -1 +1,357 @@
 package ethereum_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A handler returns the JSON-RPC result (or error) for a set of params.
+type handler func(params []json.RawMessage) (interface{}, error)
+
+// A dataError is returned by a handler to respond with an error that includes
+// data (for example, the data of a reverted call).
+type dataError struct {
+	message string
+	data    hexutil.Bytes
+}
+
+func (err dataError) Error() string {
+	return err.message
+}
+
+// newStandIn returns an HTTP server that acts as a JSON-RPC Ethereum node,
+// dispatching each request to the handler registered for its method.
+func newStandIn(handlers map[string]handler) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		h, ok := handlers[req.Method]
+		if !ok {
+			res["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		} else if result, err := h(req.Params); err != nil {
+			rpcErr := map[string]interface{}{"code": -32000, "message": err.Error()}
+			if dataErr, ok := err.(dataError); ok {
+				rpcErr["code"] = 3
+				rpcErr["data"] = dataErr.data
+			}
+			res["error"] = rpcErr
+		} else {
+			res["result"] = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+// rpcTx returns the web3 RPC transaction format of a transaction, as if it had
+// been mined in the given block.
+func rpcTx(tx *types.Transaction, blockNumber *uint64) map[string]interface{} {
+	data, err := tx.MarshalJSON()
+	Expect(err).ToNot(HaveOccurred())
+	res := map[string]interface{}{}
+	Expect(json.Unmarshal(data, &res)).To(Succeed())
+	res["blockNumber"] = nil
+	if blockNumber != nil {
+		res["blockNumber"] = hexutil.Uint64(*blockNumber)
+	}
+	return res
+}
+
+var _ = Describe("Client", func() {
+	chainID := big.NewInt(1337)
+	privKey, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(privKey.PublicKey)
+	to := common.HexToAddress("0x797522Fb74d42bB9fbF6b76dEa24D01A538d5D66")
+	signedTx, _ := types.SignTx(
+		types.NewTransaction(7, to, big.NewInt(1000), 21000, big.NewInt(1e9), []byte{0xca, 0xfe}),
+		types.NewEIP155Signer(chainID),
+		privKey,
+	)
+
+	Context("when fetching a mined transaction", func() {
+		It("should return the transaction and its confirmations", func() {
+			blockNumber := uint64(100)
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return rpcTx(signedTx, &blockNumber), nil
+				},
+				"eth_getTransactionReceipt": func([]json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{"status": "0x1"}, nil
+				},
+				"eth_blockNumber": func([]json.RawMessage) (interface{}, error) {
+					return hexutil.Uint64(104), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			tx, confs, err := client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(5)))
+			Expect(tx.Hash()).To(Equal(pack.NewBytes(signedTx.Hash().Bytes())))
+			Expect(tx.From()).To(Equal(address.Address(ethereum.Address(from).String())))
+			Expect(tx.To()).To(Equal(address.Address(ethereum.Address(to).String())))
+			Expect(tx.Value()).To(Equal(pack.NewU256FromU64(pack.NewU64(1000))))
+			Expect(tx.Nonce()).To(Equal(pack.NewU256FromU64(pack.NewU64(7))))
+			Expect(tx.Payload()).To(Equal(contract.CallData{0xca, 0xfe}))
+			Expect(tx.Sign([]pack.Bytes65{{}}, nil)).ToNot(Succeed())
+		})
+	})
+
+	Context("when fetching a pending transaction", func() {
+		It("should return zero confirmations", func() {
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return rpcTx(signedTx, nil), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, confs, err := client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(0)))
+		})
+	})
+
+	Context("when fetching a reverted transaction", func() {
+		It("should return an error", func() {
+			blockNumber := uint64(100)
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return rpcTx(signedTx, &blockNumber), nil
+				},
+				"eth_getTransactionReceipt": func([]json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{"status": "0x0"}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("reverted"))
+		})
+	})
+
+	Context("when fetching a transaction whose receipt is not indexed", func() {
+		It("should return zero confirmations", func() {
+			blockNumber := uint64(100)
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return rpcTx(signedTx, &blockNumber), nil
+				},
+				"eth_getTransactionReceipt": func([]json.RawMessage) (interface{}, error) {
+					return nil, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, confs, err := client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(0)))
+		})
+	})
+
+	Context("when fetching a transaction whose receipt has no status", func() {
+		It("should return an error that is not a revert", func() {
+			blockNumber := uint64(100)
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return rpcTx(signedTx, &blockNumber), nil
+				},
+				"eth_getTransactionReceipt": func([]json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{"blockNumber": hexutil.Uint64(blockNumber)}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).ToNot(ContainSubstring("reverted"))
+		})
+	})
+
+	Context("when fetching an unknown transaction", func() {
+		It("should return an error", func() {
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return nil, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when submitting a transaction", func() {
+		It("should send the raw transaction", func() {
+			var submitted hexutil.Bytes
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionByHash": func([]json.RawMessage) (interface{}, error) {
+					return rpcTx(signedTx, nil), nil
+				},
+				"eth_sendRawTransaction": func(params []json.RawMessage) (interface{}, error) {
+					Expect(json.Unmarshal(params[0], &submitted)).To(Succeed())
+					return signedTx.Hash(), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			tx, _, err := client.Tx(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.SubmitTx(context.Background(), tx)).To(Succeed())
+
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(submitted)).To(Equal([]byte(serial)))
+		})
+	})
+
+	Context("when fetching the account nonce", func() {
+		It("should include pending transactions", func() {
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionCount": func(params []json.RawMessage) (interface{}, error) {
+					Expect(params).To(HaveLen(2))
+					Expect(string(params[1])).To(Equal(`"pending"`))
+					return hexutil.Uint64(12), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			nonce, err := client.AccountNonce(context.Background(), address.Address(from.Hex()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nonce).To(Equal(pack.NewU256FromU64(pack.NewU64(12))))
+		})
+	})
+
+	Context("when fetching the balance", func() {
+		It("should return the balance at the latest block", func() {
+			server := newStandIn(map[string]handler{
+				"eth_getBalance": func(params []json.RawMessage) (interface{}, error) {
+					Expect(params).To(HaveLen(2))
+					Expect(string(params[1])).To(Equal(`"latest"`))
+					return (*hexutil.Big)(big.NewInt(1e18)), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			balance, err := client.AccountBalance(context.Background(), address.Address(from.Hex()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(pack.NewU256FromInt(big.NewInt(1e18))))
+		})
+	})
+
+	Context("when calling a contract", func() {
+		It("should return the output of the call", func() {
+			server := newStandIn(map[string]handler{
+				"eth_call": func(params []json.RawMessage) (interface{}, error) {
+					msg := struct {
+						To   common.Address `json:"to"`
+						Data hexutil.Bytes  `json:"data"`
+					}{}
+					Expect(json.Unmarshal(params[0], &msg)).To(Succeed())
+					Expect(msg.To).To(Equal(to))
+					Expect([]byte(msg.Data)).To(Equal([]byte{0x01, 0x02}))
+					return hexutil.Bytes{0x03, 0x04}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			output, err := client.CallContract(context.Background(), address.Address(to.Hex()), contract.CallData{0x01, 0x02})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output).To(Equal(pack.Bytes{0x03, 0x04}))
+		})
+	})
+
+	Context("when fetching the logs of a transaction", func() {
+		It("should return the logs from the receipt", func() {
+			topic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionReceipt": func([]json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{
+						"status": "0x1",
+						"logs": []map[string]interface{}{{
+							"address":         to,
+							"topics":          []common.Hash{topic},
+							"data":            hexutil.Bytes{0x01, 0x02},
+							"blockNumber":     hexutil.Uint64(100),
+							"transactionHash": signedTx.Hash(),
+							"logIndex":        hexutil.Uint64(3),
+						}},
+					}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			logs, err := client.Logs(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(Equal([]ethereum.Log{{
+				Address:     address.Address(to.Hex()),
+				Topics:      []pack.Bytes32{pack.NewBytes32(topic)},
+				Data:        pack.Bytes{0x01, 0x02},
+				BlockNumber: pack.NewU64(100),
+				TxHash:      pack.NewBytes(signedTx.Hash().Bytes()),
+				LogIndex:    pack.NewU64(3),
+			}}))
+		})
+
+		It("should return an error when the transaction has not been mined", func() {
+			server := newStandIn(map[string]handler{
+				"eth_getTransactionReceipt": func([]json.RawMessage) (interface{}, error) {
+					return nil, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.Logs(context.Background(), pack.NewBytes(signedTx.Hash().Bytes()))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
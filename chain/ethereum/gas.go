@@ -1 +1,91 @@
 package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultFeeHistoryBlockCount is the number of recent blocks that are
+	// inspected by the GasEstimator.
+	DefaultFeeHistoryBlockCount = 20
+	// DefaultRewardPercentile is the percentile of priority fees, paid in each
+	// recent block, that is used by the GasEstimator.
+	DefaultRewardPercentile = 50
+)
+
+// A GasEstimator returns the fee cap and tip cap that is needed in order to
+// confirm transactions within the next few blocks. It is based on the
+// "eth_feeHistory" of recent blocks: the tip cap is the median priority fee
+// paid at the configured percentile, and the fee cap allows for the base fee
+// to double before the transaction is no longer includable. This gives
+// approximately six consecutive full blocks of headroom.
+type GasEstimator struct {
+	client           *Client
+	blockCount       uint64
+	rewardPercentile float64
+}
+
+// NewGasEstimator returns a gas estimator that inspects the given number of
+// recent blocks, and samples the priority fees paid in those blocks at the
+// given percentile.
+func NewGasEstimator(client *Client, blockCount uint64, rewardPercentile float64) GasEstimator {
+	return GasEstimator{
+		client:           client,
+		blockCount:       blockCount,
+		rewardPercentile: rewardPercentile,
+	}
+}
+
+// EstimateGasFees returns the fee cap and the tip cap (both in wei-per-gas)
+// that should be used for EIP-1559 transactions.
+func (gasEstimator GasEstimator) EstimateGasFees(ctx context.Context) (pack.U256, pack.U256, error) {
+	baseFee, tip, err := gasEstimator.estimate(ctx)
+	if err != nil {
+		return pack.U256{}, pack.U256{}, err
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip)
+	return pack.NewU256FromInt(feeCap), pack.NewU256FromInt(tip), nil
+}
+
+// EstimateGasPrice returns the gas price (in wei-per-gas) that should be used
+// for legacy transactions. This is the base fee of the next block plus the
+// estimated tip.
+func (gasEstimator GasEstimator) EstimateGasPrice(ctx context.Context) (pack.U256, error) {
+	baseFee, tip, err := gasEstimator.estimate(ctx)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	return pack.NewU256FromInt(new(big.Int).Add(baseFee, tip)), nil
+}
+
+// estimate the base fee of the next block, and the tip.
+func (gasEstimator GasEstimator) estimate(ctx context.Context) (*big.Int, *big.Int, error) {
+	history, err := gasEstimator.client.FeeHistory(ctx, gasEstimator.blockCount, []float64{gasEstimator.rewardPercentile})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(history.BaseFeePerGas) == 0 {
+		return nil, nil, fmt.Errorf("bad fee history: no base fees")
+	}
+	baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1].Int()
+
+	tips := make([]*big.Int, 0, len(history.Reward))
+	for _, rewards := range history.Reward {
+		if len(rewards) == 0 {
+			continue
+		}
+		tips = append(tips, rewards[0].Int())
+	}
+	if len(tips) == 0 {
+		return baseFee, new(big.Int), nil
+	}
+	sort.Slice(tips, func(i, j int) bool {
+		return tips[i].Cmp(tips[j]) < 0
+	})
+	return baseFee, tips[len(tips)/2], nil
+}
@@ -1 +1,71 @@
 package ethereum_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gas", func() {
+	Context("when estimating gas fees", func() {
+		It("should use the next base fee and the median tip", func() {
+			server := newStandIn(map[string]handler{
+				"eth_feeHistory": func(params []json.RawMessage) (interface{}, error) {
+					blockCount := hexutil.Uint64(0)
+					Expect(json.Unmarshal(params[0], &blockCount)).To(Succeed())
+					Expect(blockCount).To(Equal(hexutil.Uint64(3)))
+					return map[string]interface{}{
+						"oldestBlock":   "0x64",
+						"baseFeePerGas": []string{"0x64", "0x6e", "0x78", "0x82"},
+						"gasUsedRatio":  []float64{0.5, 0.9, 0.7},
+						"reward":        [][]string{{"0x3"}, {"0x1"}, {"0x2"}},
+					}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			gasEstimator := ethereum.NewGasEstimator(client, 3, ethereum.DefaultRewardPercentile)
+
+			feeCap, tip, err := gasEstimator.EstimateGasFees(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tip).To(Equal(pack.NewU256FromU64(pack.NewU64(2))))
+			Expect(feeCap).To(Equal(pack.NewU256FromU64(pack.NewU64(2*130 + 2))))
+
+			gasPrice, err := gasEstimator.EstimateGasPrice(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gasPrice).To(Equal(pack.NewU256FromU64(pack.NewU64(130 + 2))))
+		})
+	})
+
+	Context("when the node returns no rewards", func() {
+		It("should use a zero tip", func() {
+			server := newStandIn(map[string]handler{
+				"eth_feeHistory": func([]json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{
+						"oldestBlock":   "0x64",
+						"baseFeePerGas": []string{"0x64"},
+						"gasUsedRatio":  []float64{},
+					}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL))
+			Expect(err).ToNot(HaveOccurred())
+			gasEstimator := ethereum.NewGasEstimator(client, ethereum.DefaultFeeHistoryBlockCount, ethereum.DefaultRewardPercentile)
+
+			feeCap, tip, err := gasEstimator.EstimateGasFees(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tip).To(Equal(pack.NewU256FromU64(pack.NewU64(0))))
+			Expect(feeCap).To(Equal(pack.NewU256FromU64(pack.NewU64(200))))
+		})
+	})
+})
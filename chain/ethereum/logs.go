@@ -0,0 +1,316 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+// A Log is emitted by a contract during the execution of a transaction.
+type Log = contract.Log
+
+// rpcLog is the web3 RPC log format.
+type rpcLog struct {
+	Address     common.Address `json:"address"`
+	Topics      []common.Hash  `json:"topics"`
+	Data        hexutil.Bytes  `json:"data"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"`
+}
+
+func (log rpcLog) decode() Log {
+	topics := make([]pack.Bytes32, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = pack.NewBytes32(topic)
+	}
+	return Log{
+		Address:     address.Address(Address(log.Address).String()),
+		Topics:      topics,
+		Data:        pack.NewBytes(log.Data),
+		BlockNumber: pack.NewU64(uint64(log.BlockNumber)),
+		TxHash:      pack.NewBytes(log.TxHash.Bytes()),
+		LogIndex:    pack.NewU64(uint64(log.LogIndex)),
+	}
+}
+
+// Logs returns the logs emitted by the transaction uniquely identified by the
+// given transaction hash. The transaction must have been mined.
+func (client *Client) Logs(ctx context.Context, txHash pack.Bytes) ([]Log, error) {
+	hash := common.BytesToHash(txHash)
+	raw := json.RawMessage{}
+	if err := client.rpcClient.CallContext(ctx, &raw, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, fmt.Errorf("bad \"eth_getTransactionReceipt\": %v", err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("bad \"eth_getTransactionReceipt\": %v not found", hash.Hex())
+	}
+	receipt := struct {
+		Logs []rpcLog `json:"logs"`
+	}{}
+	if err := json.Unmarshal(raw, &receipt); err != nil {
+		return nil, fmt.Errorf("bad receipt: %v", err)
+	}
+
+	logs := make([]Log, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs[i] = log.decode()
+	}
+	return logs, nil
+}
+
+// FilterLogs returns the logs selected by the filter, in the order in which
+// they were emitted. The range of blocks is split into pages, and one
+// "eth_getLogs" request is made per page. If the node rejects a page because
+// it would return too many logs, then the page is halved and the request is
+// retried. If the filter has no last block, then logs are returned up to the
+// latest block.
+func (client *Client) FilterLogs(ctx context.Context, filter contract.LogFilter) ([]Log, error) {
+	if filter.ToBlock == 0 {
+		head := hexutil.Uint64(0)
+		if err := client.rpcClient.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+			return nil, fmt.Errorf("bad \"eth_blockNumber\": %v", err)
+		}
+		filter.ToBlock = pack.NewU64(uint64(head))
+	}
+	return client.filterLogs(ctx, filter)
+}
+
+// filterLogs returns the logs selected by the filter. Unlike FilterLogs, a last
+// block of zero is the genesis block.
+func (client *Client) filterLogs(ctx context.Context, filter contract.LogFilter) ([]Log, error) {
+	if filter.ToBlock < filter.FromBlock {
+		return nil, fmt.Errorf("bad block range: expected from block %v <= to block %v", filter.FromBlock, filter.ToBlock)
+	}
+	addrs := make([]common.Address, len(filter.Addresses))
+	for i := range filter.Addresses {
+		addr, err := NewAddressFromHex(string(filter.Addresses[i]))
+		if err != nil {
+			return nil, fmt.Errorf("bad address %v: %v", i, err)
+		}
+		addrs[i] = common.Address(addr)
+	}
+	topics := make([][]common.Hash, len(filter.Topics))
+	for i := range filter.Topics {
+		// A nil set of topics is encoded as null, which matches any topic.
+		if len(filter.Topics[i]) == 0 {
+			continue
+		}
+		topics[i] = make([]common.Hash, len(filter.Topics[i]))
+		for j := range filter.Topics[i] {
+			topics[i][j] = common.Hash(filter.Topics[i][j])
+		}
+	}
+
+	pageSize := client.opts.LogPageSize
+	if pageSize == 0 {
+		pageSize = DefaultClientLogPageSize
+	}
+	logs := []Log{}
+	from, to := filter.FromBlock.Uint64(), filter.ToBlock.Uint64()
+	for from <= to {
+		pageTo := to
+		if to-from >= pageSize {
+			pageTo = from + pageSize - 1
+		}
+		query := map[string]interface{}{
+			"fromBlock": hexutil.Uint64(from),
+			"toBlock":   hexutil.Uint64(pageTo),
+			"topics":    topics,
+		}
+		if len(addrs) > 0 {
+			query["address"] = addrs
+		}
+		page := []rpcLog{}
+		if err := client.rpcClient.CallContext(ctx, &page, "eth_getLogs", query); err != nil {
+			if pageSize > 1 && isLogLimitErr(err) {
+				pageSize /= 2
+				continue
+			}
+			return nil, fmt.Errorf("bad \"eth_getLogs\": %v", err)
+		}
+		for _, log := range page {
+			logs = append(logs, log.decode())
+		}
+		if pageTo == to {
+			break
+		}
+		from = pageTo + 1
+	}
+	return logs, nil
+}
+
+// isLogLimitErr returns true if the error was returned by a node because an
+// "eth_getLogs" request would return too many logs, or covers too many blocks.
+// Nodes do not agree on a message, so the most common variations are checked.
+// Rate limiting errors are never limit errors, because a smaller page would
+// not be accepted either.
+func isLogLimitErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, substr := range []string{"rate limit", "too many requests"} {
+		if strings.Contains(msg, substr) {
+			return false
+		}
+	}
+	for _, substr := range []string{
+		"query returned more than",
+		"too many results",
+		"response size exceeded",
+		"block range",
+		"range too large",
+	} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamLogs sends the logs selected by the filter to the channel, starting
+// from the first block of the filter, and following new blocks as they are
+// mined. Logs are only sent once their block has the given number of
+// confirmations (zero confirmations is treated as one confirmation), which
+// protects against re-organisations up to that depth. If the filter has a last
+// block, then StreamLogs returns once the logs of that block have been sent.
+// Otherwise, it returns the error of the context when the context is done. The
+// channel is not closed.
+func (client *Client) StreamLogs(ctx context.Context, filter contract.LogFilter, confirmations pack.U64, logs chan<- Log) error {
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	interval := client.opts.PollInterval
+	if interval == 0 {
+		interval = DefaultClientPollInterval
+	}
+
+	next := filter.FromBlock
+	for {
+		head := hexutil.Uint64(0)
+		if err := client.rpcClient.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("bad \"eth_blockNumber\": %v", err)
+		}
+		// A block at height n has (head - n + 1) confirmations.
+		if uint64(head)+1 >= confirmations.Uint64() {
+			safe := pack.NewU64(uint64(head) + 1 - confirmations.Uint64())
+			if filter.ToBlock != 0 && safe > filter.ToBlock {
+				safe = filter.ToBlock
+			}
+			if safe >= next {
+				page := filter
+				page.FromBlock, page.ToBlock = next, safe
+				pageLogs, err := client.filterLogs(ctx, page)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					return err
+				}
+				for _, log := range pageLogs {
+					select {
+					case logs <- log:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				next = safe + 1
+			}
+		}
+		if filter.ToBlock != 0 && next > filter.ToBlock {
+			return nil
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// An EventDecoder decodes logs into events, using the events defined by a
+// contract ABI.
+type EventDecoder struct {
+	abi abi.ABI
+}
+
+// NewEventDecoder returns an EventDecoder for the events defined by the given
+// JSON encoded contract ABI.
+func NewEventDecoder(abiJSON []byte) (EventDecoder, error) {
+	contractABI, err := abi.JSON(bytes.NewReader(abiJSON))
+	if err != nil {
+		return EventDecoder{}, fmt.Errorf("bad abi: %v", err)
+	}
+	return EventDecoder{abi: contractABI}, nil
+}
+
+// Topic returns the identifier of the event with the given name. This is the
+// first topic of logs that are emitted for the event, and can be used to build
+// a contract.LogFilter.
+func (decoder EventDecoder) Topic(name string) (pack.Bytes32, error) {
+	event, ok := decoder.abi.Events[name]
+	if !ok {
+		return pack.Bytes32{}, fmt.Errorf("bad event: %v not found", name)
+	}
+	return pack.NewBytes32(event.ID), nil
+}
+
+// An Event is a log that has been decoded using a contract ABI.
+type Event struct {
+	// Name of the event.
+	Name string
+	// Args of the event, by name. Indexed args are decoded from the topics,
+	// and all other args are decoded from the data. Indexed args of a dynamic
+	// type (for example, strings) are stored as the hash of their value.
+	Args map[string]interface{}
+	Log  Log
+}
+
+// Decode the log into an event. An error is returned if the first topic of the
+// log does not identify an event in the ABI, or the topics and data of the log
+// do not match the event.
+func (decoder EventDecoder) Decode(log Log) (Event, error) {
+	if len(log.Topics) == 0 {
+		return Event{}, fmt.Errorf("bad log: expected at least 1 topic")
+	}
+	event, err := decoder.abi.EventByID(common.Hash(log.Topics[0]))
+	if err != nil {
+		return Event{}, fmt.Errorf("bad log: %v", err)
+	}
+
+	args := map[string]interface{}{}
+	if len(log.Data) > 0 {
+		if err := event.Inputs.NonIndexed().UnpackIntoMap(args, log.Data); err != nil {
+			return Event{}, fmt.Errorf("bad data: %v", err)
+		}
+	}
+	indexed := abi.Arguments{}
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if len(indexed) != len(log.Topics)-1 {
+		return Event{}, fmt.Errorf("bad topics: expected %v indexed topics, got %v", len(indexed), len(log.Topics)-1)
+	}
+	topics := make([]common.Hash, len(log.Topics)-1)
+	for i := range topics {
+		topics[i] = common.Hash(log.Topics[i+1])
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, topics); err != nil {
+		return Event{}, fmt.Errorf("bad topics: %v", err)
+	}
+	return Event{Name: event.Name, Args: args, Log: log}, nil
+}
@@ -0,0 +1,288 @@
+package ethereum_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A logQuery is the filter object of an "eth_getLogs" request.
+type logQuery struct {
+	FromBlock hexutil.Uint64   `json:"fromBlock"`
+	ToBlock   hexutil.Uint64   `json:"toBlock"`
+	Address   []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+// getLogs returns a handler for "eth_getLogs" that records each query, and
+// responds with one log at the first block of the query.
+func getLogs(queries *[]logQuery, limit uint64) handler {
+	return func(params []json.RawMessage) (interface{}, error) {
+		Expect(params).To(HaveLen(1))
+		query := logQuery{}
+		Expect(json.Unmarshal(params[0], &query)).To(Succeed())
+		*queries = append(*queries, query)
+		if limit > 0 && uint64(query.ToBlock-query.FromBlock)+1 > limit {
+			return nil, fmt.Errorf("query returned more than 10000 results")
+		}
+		return []map[string]interface{}{{
+			"address":         common.Address{},
+			"topics":          []common.Hash{{0x01}},
+			"data":            hexutil.Bytes{},
+			"blockNumber":     query.FromBlock,
+			"transactionHash": common.Hash{},
+			"logIndex":        hexutil.Uint64(0),
+		}}, nil
+	}
+}
+
+var _ = Describe("Logs", func() {
+	contractAddr := address.Address("0x797522Fb74d42bB9fbF6b76dEa24D01A538d5D66")
+	topic := pack.Bytes32{0x01}
+
+	Context("when filtering logs", func() {
+		It("should split the block range into pages", func() {
+			queries := []logQuery{}
+			server := newStandIn(map[string]handler{"eth_getLogs": getLogs(&queries, 0)})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithLogPageSize(10))
+			Expect(err).ToNot(HaveOccurred())
+			logs, err := client.FilterLogs(context.Background(), contract.LogFilter{
+				Addresses: []address.Address{contractAddr},
+				Topics:    [][]pack.Bytes32{{topic}, {}},
+				FromBlock: pack.NewU64(0),
+				ToBlock:   pack.NewU64(24),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(HaveLen(3))
+			Expect(logs[0].BlockNumber).To(Equal(pack.NewU64(0)))
+			Expect(logs[1].BlockNumber).To(Equal(pack.NewU64(10)))
+			Expect(logs[2].BlockNumber).To(Equal(pack.NewU64(20)))
+
+			Expect(queries).To(HaveLen(3))
+			Expect(queries[2].FromBlock).To(Equal(hexutil.Uint64(20)))
+			Expect(queries[2].ToBlock).To(Equal(hexutil.Uint64(24)))
+			for _, query := range queries {
+				Expect(query.Address).To(Equal([]common.Address{common.HexToAddress(string(contractAddr))}))
+				Expect(query.Topics).To(Equal([][]common.Hash{{common.Hash(topic)}, nil}))
+			}
+		})
+
+		It("should shrink the pages when the node limits the results", func() {
+			queries := []logQuery{}
+			server := newStandIn(map[string]handler{"eth_getLogs": getLogs(&queries, 4)})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithLogPageSize(10))
+			Expect(err).ToNot(HaveOccurred())
+			logs, err := client.FilterLogs(context.Background(), contract.LogFilter{
+				FromBlock: pack.NewU64(0),
+				ToBlock:   pack.NewU64(9),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			// The page is halved from 10 blocks to 5 blocks, and then to 2 blocks.
+			Expect(logs).To(HaveLen(5))
+			Expect(logs[4].BlockNumber).To(Equal(pack.NewU64(8)))
+			Expect(queries[0].Address).To(BeNil())
+		})
+
+		It("should not shrink the pages when the node is rate limiting", func() {
+			queries := 0
+			server := newStandIn(map[string]handler{
+				"eth_getLogs": func([]json.RawMessage) (interface{}, error) {
+					queries++
+					return nil, fmt.Errorf("too many requests, rate limit exceeded")
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithLogPageSize(10))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.FilterLogs(context.Background(), contract.LogFilter{
+				FromBlock: pack.NewU64(0),
+				ToBlock:   pack.NewU64(9),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("too many requests, rate limit exceeded"))
+			Expect(queries).To(Equal(1))
+		})
+
+		It("should shrink the pages when the node limits the block range", func() {
+			queries := []logQuery{}
+			server := newStandIn(map[string]handler{
+				"eth_getLogs": func(params []json.RawMessage) (interface{}, error) {
+					query := logQuery{}
+					Expect(json.Unmarshal(params[0], &query)).To(Succeed())
+					queries = append(queries, query)
+					if query.ToBlock-query.FromBlock >= 5 {
+						return nil, fmt.Errorf("exceed maximum block range: 5")
+					}
+					return []map[string]interface{}{}, nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithLogPageSize(10))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.FilterLogs(context.Background(), contract.LogFilter{
+				FromBlock: pack.NewU64(0),
+				ToBlock:   pack.NewU64(9),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queries).To(HaveLen(3))
+			Expect(queries[1].ToBlock).To(Equal(hexutil.Uint64(4)))
+		})
+
+		It("should filter up to the latest block when there is no last block", func() {
+			queries := []logQuery{}
+			server := newStandIn(map[string]handler{
+				"eth_getLogs": getLogs(&queries, 0),
+				"eth_blockNumber": func([]json.RawMessage) (interface{}, error) {
+					return hexutil.Uint64(12), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithLogPageSize(10))
+			Expect(err).ToNot(HaveOccurred())
+			logs, err := client.FilterLogs(context.Background(), contract.LogFilter{FromBlock: pack.NewU64(5)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(HaveLen(1))
+			Expect(queries).To(HaveLen(1))
+			Expect(queries[0].FromBlock).To(Equal(hexutil.Uint64(5)))
+			Expect(queries[0].ToBlock).To(Equal(hexutil.Uint64(12)))
+		})
+
+		It("should return an error for a bad block range", func() {
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.FilterLogs(context.Background(), contract.LogFilter{
+				FromBlock: pack.NewU64(10),
+				ToBlock:   pack.NewU64(9),
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when streaming logs", func() {
+		It("should only send logs once they are confirmed", func() {
+			heads := []uint64{5, 5, 12}
+			queries := []logQuery{}
+			server := newStandIn(map[string]handler{
+				"eth_getLogs": getLogs(&queries, 0),
+				"eth_blockNumber": func([]json.RawMessage) (interface{}, error) {
+					head := heads[0]
+					if len(heads) > 1 {
+						heads = heads[1:]
+					}
+					return hexutil.Uint64(head), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithPollInterval(time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			logs := make(chan ethereum.Log, 10)
+			err = client.StreamLogs(context.Background(), contract.LogFilter{
+				FromBlock: pack.NewU64(1),
+				ToBlock:   pack.NewU64(10),
+			}, pack.NewU64(3), logs)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Blocks 1 to 3 are confirmed at head 5, and blocks 4 to 10 are
+			// confirmed at head 12.
+			Expect(queries).To(HaveLen(2))
+			Expect(queries[0].FromBlock).To(Equal(hexutil.Uint64(1)))
+			Expect(queries[0].ToBlock).To(Equal(hexutil.Uint64(3)))
+			Expect(queries[1].FromBlock).To(Equal(hexutil.Uint64(4)))
+			Expect(queries[1].ToBlock).To(Equal(hexutil.Uint64(10)))
+			Expect(logs).To(HaveLen(2))
+		})
+
+		It("should return when the context is done", func() {
+			server := newStandIn(map[string]handler{
+				"eth_blockNumber": func([]json.RawMessage) (interface{}, error) {
+					return hexutil.Uint64(0), nil
+				},
+			})
+			defer server.Close()
+
+			client, err := ethereum.NewClient(ethereum.DefaultClientOptions().WithHost(server.URL).WithPollInterval(time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			err = client.StreamLogs(ctx, contract.LogFilter{FromBlock: pack.NewU64(1)}, pack.NewU64(1), make(chan ethereum.Log))
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+	})
+
+	Context("when decoding events", func() {
+		transferABI := []byte(`[{
+			"anonymous": false,
+			"name": "Transfer",
+			"type": "event",
+			"inputs": [
+				{"indexed": true, "name": "from", "type": "address"},
+				{"indexed": true, "name": "to", "type": "address"},
+				{"indexed": false, "name": "value", "type": "uint256"}
+			]
+		}]`)
+		from := common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+		to := common.HexToAddress("0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359")
+
+		It("should decode the indexed and non-indexed args", func() {
+			decoder, err := ethereum.NewEventDecoder(transferABI)
+			Expect(err).ToNot(HaveOccurred())
+			transferTopic, err := decoder.Topic("Transfer")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transferTopic).To(Equal(pack.NewBytes32(crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")))))
+
+			value, err := ethereum.Encode(pack.NewU256FromU64(pack.NewU64(1000)))
+			Expect(err).ToNot(HaveOccurred())
+			log := ethereum.Log{
+				Address: contractAddr,
+				Topics:  []pack.Bytes32{transferTopic, pack.NewBytes32(from.Hash()), pack.NewBytes32(to.Hash())},
+				Data:    pack.NewBytes(value),
+			}
+			event, err := decoder.Decode(log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(event.Name).To(Equal("Transfer"))
+			Expect(event.Args["from"]).To(Equal(from))
+			Expect(event.Args["to"]).To(Equal(to))
+			Expect(event.Args["value"]).To(Equal(big.NewInt(1000)))
+			Expect(event.Log).To(Equal(log))
+		})
+
+		It("should return an error for an unknown event", func() {
+			decoder, err := ethereum.NewEventDecoder(transferABI)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = decoder.Decode(ethereum.Log{Topics: []pack.Bytes32{topic}})
+			Expect(err).To(HaveOccurred())
+			_, err = decoder.Topic("Approval")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error when topics are missing", func() {
+			decoder, err := ethereum.NewEventDecoder(transferABI)
+			Expect(err).ToNot(HaveOccurred())
+			transferTopic, err := decoder.Topic("Transfer")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = decoder.Decode(ethereum.Log{Topics: []pack.Bytes32{transferTopic}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -2,6 +2,7 @@ package solana
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -66,26 +67,61 @@ func SendData(method string, data []byte, url string) (Response, error) {
 
 // SendDataWithRetry is the same as SendData but will retry if sending the request failed
 func SendDataWithRetry(method string, data []byte, url string) (Response, error) {
+	return SendDataWithContext(context.Background(), method, data, url)
+}
+
+// SendDataWithContext is the same as SendData, but it retries sending the
+// request until it has failed 10 times, or the context is done. Only failures
+// to send the request are retried; errors returned by the node are returned
+// immediately.
+func SendDataWithContext(ctx context.Context, method string, data []byte, url string) (Response, error) {
 	request := Request{
 		Version: "2.0",
 		ID:      1,
 		Method:  method,
 		Params:  data,
 	}
-	// Send request to lightnode with retry (max 10 times)
-	response, err := SendRequestWithRetry(request, url, 10, 10)
+	body, err := json.Marshal(request)
 	if err != nil {
-		return Response{}, fmt.Errorf("failed to send request, err = %v", err)
+		return Response{}, err
 	}
+	if !strings.HasPrefix(url, "http") {
+		url = "http://" + url
+	}
+	client := newClient(10 * time.Second)
+
+	var response *http.Response
+	for failures := 0; ; failures++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+		if err != nil {
+			return Response{}, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		response, err = client.Do(req)
+		if err == nil {
+			break
+		}
+		if failures+1 >= 10 {
+			return Response{}, fmt.Errorf("failed to send request, err = %v", err)
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return Response{}, fmt.Errorf("failed to send request, err = %v", ctx.Err())
+		}
+	}
+	defer response.Body.Close()
 
 	var resp Response
 	buf := new(bytes.Buffer)
-	buf.ReadFrom(response.Body)
+	if _, err := buf.ReadFrom(response.Body); err != nil {
+		return Response{}, fmt.Errorf("cannot read %v response body, err = %v", method, err)
+	}
 	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
 		return Response{}, fmt.Errorf("cannot decode %v response body = %s, err = %v", method, buf.String(), err)
 	}
 	if resp.Error != nil {
-		return Response{}, fmt.Errorf("got err back from %v request, err = %v", method, resp.Error)
+		return Response{}, fmt.Errorf("got err back from %v request, err = %v", method, resp.Error.Message)
 	}
 	return resp, nil
 }
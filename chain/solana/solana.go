@@ -70,3 +70,29 @@ func (client *Client) CallContract(ctx context.Context, contract address.Address
 	}
 	return pack.NewBytes(data), nil
 }
+
+// AccountBalance returns the balance of the given address, in lamports. This
+// implements the account.BalanceClient interface.
+//
+// Solana accounts do not have nonces (transactions reference a recent
+// blockhash instead), so the client does not implement AccountNonce, and
+// cannot be used with the account.NonceClient interface.
+func (client *Client) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	params, err := json.Marshal([]string{string(addr)})
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("encoding params: %v", err)
+	}
+	res, err := SendDataWithContext(ctx, "getBalance", params, client.opts.RPCURL)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("calling rpc method \"getBalance\": %v", err)
+	}
+	if res.Result == nil {
+		return pack.U256{}, fmt.Errorf("decoding result: empty")
+	}
+
+	balance := ResponseGetBalance{}
+	if err := json.Unmarshal(*res.Result, &balance); err != nil {
+		return pack.U256{}, fmt.Errorf("decoding result: %v", err)
+	}
+	return pack.NewU256FromU64(pack.NewU64(balance.Value)), nil
+}
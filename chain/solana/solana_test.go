@@ -0,0 +1,99 @@
+package solana_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/solana"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The client can report balances, but not nonces.
+var _ account.BalanceClient = &solana.Client{}
+
+// newStandIn returns a server that stands in for a Solana node, and responds
+// to each method with the given result. If the result is an error, then it is
+// returned as a JSON-RPC error.
+func newStandIn(results map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		result, ok := results[req.Method]
+		if !ok {
+			res["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+		} else if err, ok := result.(error); ok {
+			res["error"] = map[string]interface{}{"code": -32602, "message": err.Error()}
+		} else {
+			res["result"] = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+var _ = Describe("Solana", func() {
+	addr := address.Address("4Nd1mBQtrMJVYVfKf2PJy9NZUZdTAsp7D4xWLs4gDB4T")
+
+	Context("when getting the balance of an account", func() {
+		It("should return the balance in lamports", func() {
+			server := newStandIn(map[string]interface{}{
+				"getBalance": map[string]interface{}{
+					"context": map[string]interface{}{"slot": 1},
+					"value":   uint64(1000000000),
+				},
+			})
+			defer server.Close()
+
+			client := solana.NewClient(solana.ClientOptions{RPCURL: server.URL})
+			balance, err := client.AccountBalance(context.Background(), addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(pack.NewU256FromU64(pack.NewU64(1000000000))))
+		})
+
+		It("should return an error from the node without retrying", func() {
+			calls := 0
+			server := newStandIn(map[string]interface{}{})
+			handler := server.Config.Handler
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				handler.ServeHTTP(w, r)
+			})
+			defer server.Close()
+
+			client := solana.NewClient(solana.ClientOptions{RPCURL: server.URL})
+			_, err := client.AccountBalance(context.Background(), addr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Method not found"))
+			Expect(calls).To(Equal(1))
+		})
+
+		It("should stop retrying when the context is done", func() {
+			server := newStandIn(map[string]interface{}{})
+			server.Close()
+
+			client := solana.NewClient(solana.ClientOptions{RPCURL: server.URL})
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := client.AccountBalance(ctx, addr)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+	})
+})
@@ -16,3 +16,8 @@ type ResponseGetAccountInfo struct {
 	Context AccountContext `json:"context"`
 	Value   AccountValue   `json:"value"`
 }
+
+type ResponseGetBalance struct {
+	Context AccountContext `json:"context"`
+	Value   uint64         `json:"value"`
+}
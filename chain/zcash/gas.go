@@ -5,3 +5,7 @@ import "github.com/renproject/multichain/chain/bitcoin"
 type GasEstimator = bitcoin.GasEstimator
 
 var NewGasEstimator = bitcoin.NewGasEstimator
+
+type NodeGasEstimator = bitcoin.NodeGasEstimator
+
+var NewNodeGasEstimator = bitcoin.NewNodeGasEstimator
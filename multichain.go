@@ -3,12 +3,16 @@
 package multichain
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/multichain/api/address"
 	"github.com/renproject/multichain/api/contract"
 	"github.com/renproject/multichain/api/gas"
 	"github.com/renproject/multichain/api/utxo"
 	"github.com/renproject/multichain/chain/ethereum"
+	"github.com/renproject/pack"
 	"github.com/renproject/surge"
 )
 
@@ -20,9 +24,11 @@ type (
 )
 
 type (
-	AccountTx        = account.Tx
-	AccountTxBuilder = account.TxBuilder
-	AccountClient    = account.Client
+	AccountTx            = account.Tx
+	AccountTxBuilder     = account.TxBuilder
+	AccountClient        = account.Client
+	AccountNonceClient   = account.NonceClient
+	AccountBalanceClient = account.BalanceClient
 )
 
 type (
@@ -36,12 +42,17 @@ type (
 )
 
 type (
-	ContractCallData = contract.CallData
-	ContractCaller   = contract.Caller
+	ContractCallData    = contract.CallData
+	ContractCaller      = contract.Caller
+	ContractCallerAt    = contract.CallerAt
+	ContractLog         = contract.Log
+	ContractLogFilter   = contract.LogFilter
+	ContractLogFilterer = contract.LogFilterer
 )
 
 type (
-	GasEstimator = gas.Estimator
+	GasEstimator           = gas.Estimator
+	GasDynamicFeeEstimator = gas.DynamicFeeEstimator
 )
 
 // An Asset uniquely identifies assets using human-readable strings.
@@ -97,10 +108,66 @@ func (asset Asset) OriginChain() Chain {
 	case ZEC:
 		return Zcash
 	default:
+		if token, ok := asset.Token(); ok {
+			return token.Chain
+		}
 		return Chain("")
 	}
 }
 
+// A Token describes an asset that is issued by a contract on a chain, rather
+// than being native to the chain. For example, an ERC-20 token on Ethereum.
+type Token struct {
+	Chain    Chain
+	Contract Address
+	Decimals pack.U8
+}
+
+var (
+	tokensMu = new(sync.RWMutex)
+	tokens   = map[Asset]Token{}
+)
+
+// RegisterToken registers the asset as a token issued by a contract on a
+// chain. Once registered, the origin chain of the asset is the chain of the
+// token. An error is returned if the asset is a native asset, or if the asset
+// has already been registered as a different token.
+func RegisterToken(asset Asset, token Token) error {
+	if asset == "" {
+		return fmt.Errorf("bad asset: empty")
+	}
+	if token.Chain == "" {
+		return fmt.Errorf("bad chain: empty")
+	}
+	if token.Contract == "" {
+		return fmt.Errorf("bad contract: empty")
+	}
+
+	// Unregistered assets only have an origin chain if they are native.
+	if _, ok := asset.Token(); !ok && asset.OriginChain() != "" {
+		return fmt.Errorf("bad asset: %v is native to %v", asset, asset.OriginChain())
+	}
+
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	if registered, ok := tokens[asset]; ok && registered != token {
+		return fmt.Errorf("bad asset: %v is already registered on %v at %v", asset, registered.Chain, registered.Contract)
+	}
+	tokens[asset] = token
+	return nil
+}
+
+// Token returns the token that has been registered for the asset. It returns
+// false if the asset has not been registered as a token.
+func (asset Asset) Token() (Token, bool) {
+	tokensMu.RLock()
+	defer tokensMu.RUnlock()
+
+	token, ok := tokens[asset]
+	return token, ok
+}
+
 // SizeHint returns the number of bytes required to represent the asset in
 // binary.
 func (asset Asset) SizeHint() int {
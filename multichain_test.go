@@ -1,4 +1,50 @@
 package multichain_test
 
+import (
+	"github.com/renproject/multichain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
 // TODO: Run test suite of simple tests for all supported chains. The idea is to
 // use the common APIs to run these tests.
+
+var _ = Describe("Token", func() {
+	dai := multichain.Token{
+		Chain:    multichain.Ethereum,
+		Contract: multichain.Address("0x6B175474E89094C44Da98b954EedeAC495271d0F"),
+		Decimals: 18,
+	}
+
+	Context("when registering a token", func() {
+		It("should use the chain of the token as the origin chain", func() {
+			asset := multichain.Asset("DAI")
+			Expect(asset.OriginChain()).To(Equal(multichain.Chain("")))
+			Expect(multichain.RegisterToken(asset, dai)).To(Succeed())
+
+			token, ok := asset.Token()
+			Expect(ok).To(BeTrue())
+			Expect(token).To(Equal(dai))
+			Expect(asset.OriginChain()).To(Equal(multichain.Ethereum))
+
+			// Registering the same token again is allowed, but registering a
+			// different token is not.
+			Expect(multichain.RegisterToken(asset, dai)).To(Succeed())
+			usdc := dai
+			usdc.Contract = multichain.Address("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+			Expect(multichain.RegisterToken(asset, usdc)).ToNot(Succeed())
+		})
+
+		It("should return an error for a native asset", func() {
+			Expect(multichain.RegisterToken(multichain.ETH, dai)).ToNot(Succeed())
+			_, ok := multichain.ETH.Token()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should return an error for an incomplete token", func() {
+			Expect(multichain.RegisterToken(multichain.Asset("USDT"), multichain.Token{Chain: multichain.Ethereum})).ToNot(Succeed())
+			Expect(multichain.RegisterToken(multichain.Asset("USDT"), multichain.Token{Contract: dai.Contract})).ToNot(Succeed())
+		})
+	})
+})